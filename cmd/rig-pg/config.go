@@ -0,0 +1,164 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bradleybonitatibus/rig/pg"
+)
+
+// connFlags are the flags every command uses to build its ConnectionConfig.
+type connFlags struct {
+	fs        *flag.FlagSet
+	url       string
+	envPrefix string
+	host      string
+	port      int
+	user      string
+	dbname    string
+	sslmode   string
+	service   string
+	appName   string
+}
+
+// addConnFlags registers the connection flags on fs.
+func addConnFlags(fs *flag.FlagSet) *connFlags {
+	f := &connFlags{fs: fs}
+	fs.StringVar(&f.url, "url", "", "connection `dsn` as a postgres:// URL or keyword/value string (default $DATABASE_URL)")
+	fs.StringVar(&f.envPrefix, "env-prefix", pg.DefaultEnvPrefix, "`prefix` of the environment variables read when no URL is given")
+	fs.StringVar(&f.host, "host", "", "server host, or comma separated hosts")
+	fs.IntVar(&f.port, "port", 0, "server port")
+	fs.StringVar(&f.user, "user", "", "user name")
+	fs.StringVar(&f.dbname, "dbname", "", "database name")
+	fs.StringVar(&f.sslmode, "sslmode", "", "SSL mode")
+	fs.StringVar(&f.service, "service", "", "pg_service.conf service name")
+	fs.StringVar(&f.appName, "application-name", "", "application name reported to the server")
+	return f
+}
+
+// config resolves the ConnectionConfig of the flags. It starts from the URL,
+// or from the environment when no URL is given, overrides it with the
+// connection flags that were set, and then fills in the missing fields from
+// pg_service.conf and .pgpass. Warnings are written to the output of the
+// flag set.
+func (f *connFlags) config() (*pg.ConnectionConfig, error) {
+	dsn := f.url
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+
+	var (
+		cfg *pg.ConnectionConfig
+		err error
+	)
+	if dsn != "" {
+		cfg, err = pg.ParseDSN(dsn)
+	} else {
+		cfg, err = pg.ConfigFromEnvWithPrefix(f.envPrefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "user":
+			cfg.User = f.user
+		case "dbname":
+			cfg.Database = f.dbname
+		case "sslmode":
+			cfg.SSLMode = pg.SSLMode(f.sslmode)
+		case "service":
+			cfg.Service = f.service
+		case "application-name":
+			cfg.ApplicationName = f.appName
+		}
+	})
+	f.overrideAddrs(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	// Like libpq, problems that do not stop the resolution, such as an
+	// insecure password file, are printed as warnings.
+	r := pg.Resolver{OnWarning: func(err error) {
+		fmt.Fprintf(f.fs.Output(), "rig-pg: warning: %v\n", err)
+	}}
+	if err := r.Resolve(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// overrideAddrs applies the -host and -port flags to cfg. A -host flag
+// replaces every host of cfg, keeping the port of the first one, and a -port
+// flag sets the port of every host.
+func (f *connFlags) overrideAddrs(cfg *pg.ConnectionConfig) {
+	addrs := cfg.Addrs()
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name != "host" {
+			return
+		}
+		port := addrs[0].Port
+		addrs = nil
+		for _, h := range strings.Split(f.host, ",") {
+			addrs = append(addrs, pg.HostPort{Host: h, Port: port})
+		}
+	})
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name != "port" {
+			return
+		}
+		for i := range addrs {
+			addrs[i].Port = f.port
+		}
+	})
+
+	cfg.Host, cfg.Port, cfg.Hosts = "", 0, nil
+	if len(addrs) == 1 {
+		cfg.Host, cfg.Port = addrs[0].Host, addrs[0].Port
+	} else {
+		cfg.Hosts = addrs
+	}
+}
+
+// formatConfig renders cfg in format, which is one of "odbc", "url" or
+// "json". Unless showPassword is set, the password is redacted.
+func formatConfig(cfg *pg.ConnectionConfig, format string, showPassword bool) (string, error) {
+	c := *cfg
+	if !showPassword && c.Password != "" {
+		c.Password = pg.RedactedPassword
+	}
+	switch format {
+	case "odbc":
+		return c.ODBC(), nil
+	case "url":
+		return c.URL(), nil
+	case "json":
+		b, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected odbc, url or json", format)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/rig/pg"
+)
+
+// isolateEnv clears the environment variables and files that rig-pg reads
+// the connection config from, including the POSTGRES_* variables that the
+// Makefile and CI set for the integration tests.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "")
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "PG") || strings.HasPrefix(name, "POSTGRES_") {
+			t.Setenv(name, "")
+		}
+	}
+	dir := t.TempDir()
+	t.Setenv("PGPASSFILE", filepath.Join(dir, "pgpass"))
+	t.Setenv("PGSERVICEFILE", filepath.Join(dir, "pg_service.conf"))
+}
+
+func TestConnFlags_Config(t *testing.T) {
+	type test struct {
+		name string
+		env  map[string]string
+		args []string
+		want pg.ConnectionConfig
+	}
+	tests := []test{
+		{
+			name: "environment",
+			env:  map[string]string{"PGHOST": "db", "PGUSER": "app", "PGPORT": "6432"},
+			want: pg.ConnectionConfig{Host: "db", User: "app", Port: 6432},
+		},
+		{
+			name: "environment prefix",
+			env:  map[string]string{"POSTGRES_HOST": "db", "POSTGRES_DB": "app"},
+			args: []string{"-env-prefix", "POSTGRES_"},
+			want: pg.ConnectionConfig{Host: "db", Database: "app"},
+		},
+		{
+			name: "database url takes precedence over environment",
+			env:  map[string]string{"PGHOST": "ignored", "DATABASE_URL": "postgres://app@db/app"},
+			want: pg.ConnectionConfig{Host: "db", User: "app", Database: "app"},
+		},
+		{
+			name: "flags override url",
+			args: []string{
+				"-url", "host=db port=6432 user=app dbname=app",
+				"-user", "admin", "-dbname", "ops", "-sslmode", "require",
+			},
+			want: pg.ConnectionConfig{Host: "db", Port: 6432, User: "admin", Database: "ops", SSLMode: pg.SSLModeRequire},
+		},
+		{
+			name: "host flag keeps port",
+			args: []string{"-url", "postgres://db:6432", "-host", "a,b"},
+			want: pg.ConnectionConfig{Hosts: []pg.HostPort{{Host: "a", Port: 6432}, {Host: "b", Port: 6432}}},
+		},
+		{
+			name: "port flag sets every port",
+			args: []string{"-url", "postgres://a:1,b:2", "-port", "5433"},
+			want: pg.ConnectionConfig{Hosts: []pg.HostPort{{Host: "a", Port: 5433}, {Host: "b", Port: 5433}}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isolateEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			conn := addConnFlags(fs)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := conn.config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("expected %#v, got %#v instead", tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestConnFlags_ConfigInvalid(t *testing.T) {
+	isolateEnv(t)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	conn := addConnFlags(fs)
+	if err := fs.Parse([]string{"-host", "db", "-sslmode", "sometimes"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.config(); err == nil {
+		t.Error("expected an invalid sslmode to be rejected")
+	}
+}
+
+func TestConnFlags_ConfigInsecurePassFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("password file permissions are not checked on windows")
+	}
+	isolateEnv(t)
+	path := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(path, []byte("*:*:*:*:secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGPASSFILE", path)
+
+	var stderr strings.Builder
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&stderr)
+	conn := addConnFlags(fs)
+	if err := fs.Parse([]string{"-host", "db"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := conn.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "" {
+		t.Errorf("expected the insecure password file to be skipped, got %q", cfg.Password)
+	}
+	if !strings.Contains(stderr.String(), "warning: pg: password file has group or world access") {
+		t.Errorf("expected a warning, got %q instead", stderr.String())
+	}
+}
+
+func TestFormatConfig(t *testing.T) {
+	cfg := &pg.ConnectionConfig{Host: "db", User: "app", Password: "secret", Database: "app"}
+	type test struct {
+		format       string
+		showPassword bool
+		want         string
+	}
+	tests := []test{
+		{format: "odbc", want: "host=db port=5432 user=app dbname=app password=********"},
+		{format: "odbc", showPassword: true, want: "host=db port=5432 user=app dbname=app password=secret"},
+		{format: "url", want: "postgres://app:%2A%2A%2A%2A%2A%2A%2A%2A@db/app"},
+		{format: "url", showPassword: true, want: "postgres://app:secret@db/app"},
+	}
+	for _, tc := range tests {
+		got, err := formatConfig(cfg, tc.format, tc.showPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("expected %v, got %v instead", tc.want, got)
+		}
+	}
+	if _, err := formatConfig(cfg, "yaml", false); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
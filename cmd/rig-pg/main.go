@@ -0,0 +1,266 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command rig-pg resolves Postgres connection configs, checks connectivity
+// and runs schema migrations with package pg.
+//
+// Usage:
+//
+//	rig-pg <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	config            print the resolved connection config
+//	ping              connect to the database and report the server
+//	migrate up        apply every pending migration
+//	migrate down      revert the latest applied migration
+//	migrate to N      migrate up or down to version N
+//	migrate status    list the migrations and their state
+//
+// The connection config is read from the -url flag, $DATABASE_URL or the
+// libpq PG* environment variables, in that order, with the connection flags
+// overriding it. Missing fields are then filled in from pg_service.conf and
+// .pgpass. Passwords are redacted from the output unless -show-password is
+// given.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bradleybonitatibus/rig/pg"
+	_ "github.com/lib/pq"
+)
+
+// errUsage is returned by a command when it is given invalid arguments,
+// after the usage has been printed.
+var errUsage = errors.New("usage")
+
+const usage = `usage: rig-pg <command> [flags] [arguments]
+
+commands:
+  config            print the resolved connection config
+  ping              connect to the database and report the server
+  migrate up        apply every pending migration
+  migrate down      revert the latest applied migration
+  migrate to N      migrate up or down to version N
+  migrate status    list the migrations and their state
+
+Run "rig-pg <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command in args, and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	commands := map[string]func([]string, io.Writer, io.Writer) error{
+		"config":  runConfig,
+		"ping":    runPing,
+		"migrate": runMigrate,
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "rig-pg: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	switch err := cmd(args[1:], stdout, stderr); {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "rig-pg %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+// newFlagSet returns a flag set for the named command, which prints its
+// errors and usage to stderr.
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: rig-pg %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runConfig(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("config", "", stderr)
+	conn := addConnFlags(fs)
+	format := fs.String("format", "odbc", "output `format`, one of odbc, url or json")
+	showPassword := fs.Bool("show-password", false, "print the password instead of redacting it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, err := conn.config()
+	if err != nil {
+		return err
+	}
+	out, err := formatConfig(cfg, *format, *showPassword)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, out)
+	return nil
+}
+
+func runPing(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ping", "", stderr)
+	conn := addConnFlags(fs)
+	driver := fs.String("driver", "postgres", "database/sql `driver` to connect with")
+	timeout := fs.Duration("timeout", 10*time.Second, "how long to wait for the server")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, err := conn.config()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	db, err := pg.Open(ctx, *driver, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+		return err
+	}
+	host, _ := db.LastHost()
+	fmt.Fprintf(stdout, "connected to %s as %s\n%s\n", host, cfg.User, version)
+	return nil
+}
+
+func runMigrate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("migrate", "up|down|to N|status", stderr)
+	conn := addConnFlags(fs)
+	driver := fs.String("driver", "postgres", "database/sql `driver` to connect with")
+	dir := fs.String("dir", "migrations", "`directory` of the migration files")
+	table := fs.String("table", pg.DefaultMigrationsTable, "`table` recording the applied migrations")
+	timeout := fs.Duration("timeout", 0, "how long to wait for the migrations, zero waits indefinitely")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var version int64
+	switch {
+	case fs.NArg() == 1 && (fs.Arg(0) == "up" || fs.Arg(0) == "down" || fs.Arg(0) == "status"):
+	case fs.NArg() == 2 && fs.Arg(0) == "to":
+		v, err := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if err != nil || v < 0 {
+			fmt.Fprintf(stderr, "invalid version %q\n", fs.Arg(1))
+			return errUsage
+		}
+		version = v
+	default:
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, err := conn.config()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	db, err := pg.Open(ctx, *driver, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	m, err := pg.NewMigrator(db.DB, os.DirFS(*dir), pg.MigratorOptions{Table: *table})
+	if err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		n, err := m.Up(ctx)
+		fmt.Fprintf(stdout, "applied %d migrations\n", n)
+		return err
+	case "down":
+		n, err := m.Down(ctx)
+		fmt.Fprintf(stdout, "reverted %d migrations\n", n)
+		return err
+	case "to":
+		n, err := m.To(ctx, version)
+		fmt.Fprintf(stdout, "ran %d migrations towards version %d\n", n, version)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(stdout, statuses)
+	}
+	return nil
+}
+
+// printStatus prints a table of the migration statuses.
+func printStatus(w io.Writer, statuses []pg.MigrationStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Modified:
+			state = "modified"
+		case s.Missing:
+			state = "missing"
+		case s.OutOfOrder:
+			state = "out of order"
+		case s.Applied:
+			state = "applied"
+		}
+		appliedAt := ""
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return tw.Flush()
+}
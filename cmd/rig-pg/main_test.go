@@ -0,0 +1,111 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/rig/pg"
+)
+
+func TestRun(t *testing.T) {
+	type test struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}
+	tests := []test{
+		{
+			name:   "no command",
+			code:   2,
+			stderr: "usage: rig-pg",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"vacuum"},
+			code:   2,
+			stderr: `unknown command "vacuum"`,
+		},
+		{
+			name:   "config",
+			args:   []string{"config", "-url", "postgres://app:secret@db/app"},
+			stdout: "host=db port=5432 user=app dbname=app password=********\n",
+		},
+		{
+			name:   "config help",
+			args:   []string{"config", "-h"},
+			code:   2,
+			stderr: "-show-password",
+		},
+		{
+			name:   "config error",
+			args:   []string{"config", "-url", "postgres://db/app?sslmode=sometimes"},
+			code:   1,
+			stderr: "rig-pg config: ",
+		},
+		{
+			name:   "migrate without subcommand",
+			args:   []string{"migrate"},
+			code:   2,
+			stderr: "usage: rig-pg migrate",
+		},
+		{
+			name:   "migrate to invalid version",
+			args:   []string{"migrate", "to", "latest"},
+			code:   2,
+			stderr: `invalid version "latest"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isolateEnv(t)
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, &stdout, &stderr); code != tc.code {
+				t.Errorf("expected exit code %v, got %v instead: %s", tc.code, code, stderr.String())
+			}
+			if tc.stdout != "" && stdout.String() != tc.stdout {
+				t.Errorf("expected %q, got %q instead", tc.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("expected stderr to contain %q, got %q instead", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_ConfigJSON(t *testing.T) {
+	isolateEnv(t)
+	var stdout, stderr bytes.Buffer
+	args := []string{"config", "-format", "json", "-url", "postgres://app:secret@db:6432/app"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0, got %v instead: %s", code, stderr.String())
+	}
+	var got pg.ConnectionConfig
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := pg.ConnectionConfig{Host: "db", Port: 6432, User: "app", Password: pg.RedactedPassword, Database: "app"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v instead", want, got)
+	}
+}
@@ -0,0 +1,568 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"sync"
+
+	"github.com/bradleybonitatibus/rig/algorithm"
+)
+
+// btreeDegree is the minimum degree of a BTreeMap. Every node other than the
+// root holds between btreeDegree-1 and 2*btreeDegree-1 entries.
+const btreeDegree = 16
+
+// btreeEntry is a key value pair stored in a btreeNode.
+type btreeEntry[K any, V any] struct {
+	key   K
+	value V
+}
+
+// btreeNode is a node of a BTreeMap. size is the number of entries in the
+// subtree rooted at the node, and is what allows Rank and Select to run in
+// logarithmic time.
+type btreeNode[K any, V any] struct {
+	entries  []btreeEntry[K, V]
+	children []*btreeNode[K, V]
+	size     int
+}
+
+// BTreeMap is a thread safe ordered map backed by a B-tree. Keys are kept in
+// the order defined by less, and two keys are considered equal when neither
+// is less than the other.
+type BTreeMap[K any, V any] struct {
+	mu   sync.RWMutex
+	less func(a, b K) bool
+	root *btreeNode[K, V]
+}
+
+// NewBTreeMap creates an empty map ordered by less.
+func NewBTreeMap[K any, V any](less func(a, b K) bool) *BTreeMap[K, V] {
+	return &BTreeMap[K, V]{
+		mu:   sync.RWMutex{},
+		less: less,
+		root: nil,
+	}
+}
+
+// NewOrderedBTreeMap creates an empty map for ordered key types, sorted in
+// ascending order.
+func NewOrderedBTreeMap[K algorithm.Ordered, V any]() *BTreeMap[K, V] {
+	return NewBTreeMap[K, V](func(a, b K) bool {
+		return a < b
+	})
+}
+
+// Len returns the number of entries in the map.
+func (m *BTreeMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.root == nil {
+		return 0
+	}
+	return m.root.size
+}
+
+// Get returns the value stored for key. If key is not in the map, it will
+// return the "default" value of type V, and false.
+func (m *BTreeMap[K, V]) Get(key K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if e := m.find(key); e != nil {
+		return e.value, true
+	}
+	var empty V
+	return empty, false
+}
+
+// Set stores value for key, replacing any existing value. It returns true if
+// key was not previously in the map.
+func (m *BTreeMap[K, V]) Set(key K, value V) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.find(key); e != nil {
+		e.value = value
+		return false
+	}
+	e := btreeEntry[K, V]{key: key, value: value}
+	if m.root == nil {
+		m.root = &btreeNode[K, V]{entries: []btreeEntry[K, V]{e}, size: 1}
+		return true
+	}
+	if len(m.root.entries) == 2*btreeDegree-1 {
+		root := &btreeNode[K, V]{
+			children: []*btreeNode[K, V]{m.root},
+			size:     m.root.size,
+		}
+		m.split(root, 0)
+		m.root = root
+	}
+	m.insert(m.root, e)
+	return true
+}
+
+// Delete removes key from the map and returns the value that was stored for
+// it. If key is not in the map, it will return the "default" value of type V,
+// and false.
+func (m *BTreeMap[K, V]) Delete(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.find(key)
+	if e == nil {
+		var empty V
+		return empty, false
+	}
+	value := e.value
+	m.remove(m.root, key)
+	if len(m.root.entries) == 0 {
+		if len(m.root.children) > 0 {
+			m.root = m.root.children[0]
+		} else {
+			m.root = nil
+		}
+	}
+	return value, true
+}
+
+// Min returns the smallest key in the map and its value. If the map is
+// empty, it will return the "default" values of type K and V, and false.
+func (m *BTreeMap[K, V]) Min() (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.root == nil {
+		return m.none()
+	}
+	n := m.root
+	for len(n.children) > 0 {
+		n = n.children[0]
+	}
+	return n.entries[0].key, n.entries[0].value, true
+}
+
+// Max returns the largest key in the map and its value. If the map is
+// empty, it will return the "default" values of type K and V, and false.
+func (m *BTreeMap[K, V]) Max() (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.root == nil {
+		return m.none()
+	}
+	e := m.root.max()
+	return e.key, e.value, true
+}
+
+// Floor returns the largest key in the map that is less than or equal to key,
+// and its value. If there is no such key, it will return the "default" values
+// of type K and V, and false.
+func (m *BTreeMap[K, V]) Floor(key K) (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var found *btreeEntry[K, V]
+	for n := m.root; n != nil; {
+		i := m.upperBound(n, key)
+		if i > 0 {
+			found = &n.entries[i-1]
+		}
+		if len(n.children) == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	if found == nil {
+		return m.none()
+	}
+	return found.key, found.value, true
+}
+
+// Ceiling returns the smallest key in the map that is greater than or equal
+// to key, and its value. If there is no such key, it will return the
+// "default" values of type K and V, and false.
+func (m *BTreeMap[K, V]) Ceiling(key K) (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var found *btreeEntry[K, V]
+	for n := m.root; n != nil; {
+		i := m.lowerBound(n, key)
+		if i < len(n.entries) {
+			found = &n.entries[i]
+		}
+		if len(n.children) == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	if found == nil {
+		return m.none()
+	}
+	return found.key, found.value, true
+}
+
+// Rank returns the number of keys in the map that are strictly less than key.
+// If key is in the map, this is its zero-based index in ascending order.
+func (m *BTreeMap[K, V]) Rank(key K) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rank := 0
+	for n := m.root; n != nil; {
+		i := m.lowerBound(n, key)
+		rank += i
+		if len(n.children) == 0 {
+			break
+		}
+		for _, c := range n.children[:i] {
+			rank += c.size
+		}
+		n = n.children[i]
+	}
+	return rank
+}
+
+// Select returns the key and value at the zero-based index i in ascending
+// order. If i is out of range, it will return the "default" values of type
+// K and V, and false.
+func (m *BTreeMap[K, V]) Select(i int) (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.root == nil || i < 0 || i >= m.root.size {
+		return m.none()
+	}
+	n := m.root
+	for {
+		if len(n.children) == 0 {
+			return n.entries[i].key, n.entries[i].value, true
+		}
+		for j, c := range n.children {
+			if i < c.size {
+				n = c
+				break
+			}
+			i -= c.size
+			if i == 0 {
+				return n.entries[j].key, n.entries[j].value, true
+			}
+			i--
+		}
+	}
+}
+
+// Ascend calls fn for every entry in ascending key order until fn returns
+// false. fn must not modify the map.
+func (m *BTreeMap[K, V]) Ascend(fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.ascend(m.root, nil, nil, fn)
+}
+
+// AscendRange calls fn in ascending key order for every entry whose key is in
+// the range [greaterOrEqual, lessThan), until fn returns false. fn must not
+// modify the map.
+func (m *BTreeMap[K, V]) AscendRange(greaterOrEqual, lessThan K, fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.ascend(m.root, &greaterOrEqual, &lessThan, fn)
+}
+
+// Descend calls fn for every entry in descending key order until fn returns
+// false. fn must not modify the map.
+func (m *BTreeMap[K, V]) Descend(fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.descend(m.root, nil, nil, fn)
+}
+
+// DescendRange calls fn in descending key order for every entry whose key is
+// in the range (greaterThan, lessOrEqual], until fn returns false. fn must not
+// modify the map.
+func (m *BTreeMap[K, V]) DescendRange(lessOrEqual, greaterThan K, fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.descend(m.root, &lessOrEqual, &greaterThan, fn)
+}
+
+// none returns the "default" values of type K and V, and false.
+func (m *BTreeMap[K, V]) none() (K, V, bool) {
+	var (
+		key   K
+		value V
+	)
+	return key, value, false
+}
+
+// find returns the entry stored for key, or nil. The caller must hold the
+// lock.
+func (m *BTreeMap[K, V]) find(key K) *btreeEntry[K, V] {
+	for n := m.root; n != nil; {
+		i := m.lowerBound(n, key)
+		if i < len(n.entries) && !m.less(key, n.entries[i].key) {
+			return &n.entries[i]
+		}
+		if len(n.children) == 0 {
+			return nil
+		}
+		n = n.children[i]
+	}
+	return nil
+}
+
+// lowerBound returns the index of the first entry in n whose key is not less
+// than key.
+func (m *BTreeMap[K, V]) lowerBound(n *btreeNode[K, V], key K) int {
+	lo, hi := 0, len(n.entries)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if m.less(n.entries[mid].key, key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// upperBound returns the index of the first entry in n whose key is greater
+// than key.
+func (m *BTreeMap[K, V]) upperBound(n *btreeNode[K, V], key K) int {
+	lo, hi := 0, len(n.entries)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if !m.less(key, n.entries[mid].key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// insert adds e, whose key must not be in the map, to the subtree rooted at
+// n, which must not be full.
+func (m *BTreeMap[K, V]) insert(n *btreeNode[K, V], e btreeEntry[K, V]) {
+	for {
+		n.size++
+		i := m.lowerBound(n, e.key)
+		if len(n.children) == 0 {
+			n.entries = append(n.entries, btreeEntry[K, V]{})
+			copy(n.entries[i+1:], n.entries[i:])
+			n.entries[i] = e
+			return
+		}
+		if len(n.children[i].entries) == 2*btreeDegree-1 {
+			m.split(n, i)
+			if m.less(n.entries[i].key, e.key) {
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// split divides the full child i of n into two nodes, moving its median entry
+// up into n.
+func (m *BTreeMap[K, V]) split(n *btreeNode[K, V], i int) {
+	child := n.children[i]
+	median := child.entries[btreeDegree-1]
+	right := &btreeNode[K, V]{
+		entries: append([]btreeEntry[K, V]{}, child.entries[btreeDegree:]...),
+	}
+	child.entries = append([]btreeEntry[K, V]{}, child.entries[:btreeDegree-1]...)
+	if len(child.children) > 0 {
+		right.children = append([]*btreeNode[K, V]{}, child.children[btreeDegree:]...)
+		child.children = append([]*btreeNode[K, V]{}, child.children[:btreeDegree]...)
+	}
+	child.resize()
+	right.resize()
+
+	n.entries = append(n.entries, btreeEntry[K, V]{})
+	copy(n.entries[i+1:], n.entries[i:])
+	n.entries[i] = median
+	n.children = append(n.children, nil)
+	copy(n.children[i+2:], n.children[i+1:])
+	n.children[i+1] = right
+}
+
+// remove deletes key, which must be in the map, from the subtree rooted at n.
+// Every node that is descended into is first topped up to hold at least
+// btreeDegree entries, so that removing from it never leaves it underfull.
+func (m *BTreeMap[K, V]) remove(n *btreeNode[K, V], key K) {
+	for {
+		n.size--
+		i := m.lowerBound(n, key)
+		found := i < len(n.entries) && !m.less(key, n.entries[i].key)
+		if len(n.children) == 0 {
+			n.entries = append(n.entries[:i], n.entries[i+1:]...)
+			return
+		}
+		if found {
+			left, right := n.children[i], n.children[i+1]
+			switch {
+			case len(left.entries) >= btreeDegree:
+				n.entries[i] = left.max()
+				n, key = left, n.entries[i].key
+			case len(right.entries) >= btreeDegree:
+				n.entries[i] = right.min()
+				n, key = right, n.entries[i].key
+			default:
+				m.merge(n, i)
+				n = left
+			}
+			continue
+		}
+		if len(n.children[i].entries) < btreeDegree {
+			i = m.fill(n, i)
+		}
+		n = n.children[i]
+	}
+}
+
+// fill makes sure child i of n holds at least btreeDegree entries, by either
+// borrowing an entry from a sibling or merging with one. It returns the index
+// of the child that now holds the keys that used to be in child i.
+func (m *BTreeMap[K, V]) fill(n *btreeNode[K, V], i int) int {
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].entries) >= btreeDegree:
+		left := n.children[i-1]
+		child.entries = append([]btreeEntry[K, V]{n.entries[i-1]}, child.entries...)
+		n.entries[i-1] = left.entries[len(left.entries)-1]
+		left.entries = left.entries[:len(left.entries)-1]
+		moved := 1
+		if len(left.children) > 0 {
+			last := left.children[len(left.children)-1]
+			left.children = left.children[:len(left.children)-1]
+			child.children = append([]*btreeNode[K, V]{last}, child.children...)
+			moved += last.size
+		}
+		child.size += moved
+		left.size -= moved
+		return i
+	case i < len(n.entries) && len(n.children[i+1].entries) >= btreeDegree:
+		right := n.children[i+1]
+		child.entries = append(child.entries, n.entries[i])
+		n.entries[i] = right.entries[0]
+		right.entries = append(right.entries[:0], right.entries[1:]...)
+		moved := 1
+		if len(right.children) > 0 {
+			first := right.children[0]
+			right.children = append(right.children[:0], right.children[1:]...)
+			child.children = append(child.children, first)
+			moved += first.size
+		}
+		child.size += moved
+		right.size -= moved
+		return i
+	case i < len(n.entries):
+		m.merge(n, i)
+		return i
+	default:
+		m.merge(n, i-1)
+		return i - 1
+	}
+}
+
+// merge joins child i+1 of n and the entry separating it from child i into
+// child i.
+func (m *BTreeMap[K, V]) merge(n *btreeNode[K, V], i int) {
+	left, right := n.children[i], n.children[i+1]
+	left.entries = append(left.entries, n.entries[i])
+	left.entries = append(left.entries, right.entries...)
+	left.children = append(left.children, right.children...)
+	left.size += 1 + right.size
+	n.entries = append(n.entries[:i], n.entries[i+1:]...)
+	n.children = append(n.children[:i+1], n.children[i+2:]...)
+}
+
+// ascend walks the subtree rooted at n in ascending order, calling fn for the
+// entries in [lo, hi). A nil bound is unbounded. It returns false once fn has
+// asked to stop or the upper bound was reached.
+func (m *BTreeMap[K, V]) ascend(n *btreeNode[K, V], lo, hi *K, fn func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	i := 0
+	if lo != nil {
+		i = m.lowerBound(n, *lo)
+	}
+	for ; i < len(n.entries); i++ {
+		if len(n.children) > 0 && !m.ascend(n.children[i], lo, hi, fn) {
+			return false
+		}
+		e := n.entries[i]
+		if hi != nil && !m.less(e.key, *hi) {
+			return false
+		}
+		if !fn(e.key, e.value) {
+			return false
+		}
+	}
+	if len(n.children) > 0 {
+		return m.ascend(n.children[len(n.entries)], lo, hi, fn)
+	}
+	return true
+}
+
+// descend walks the subtree rooted at n in descending order, calling fn for
+// the entries in (lo, hi]. A nil bound is unbounded. It returns false once fn
+// has asked to stop or the lower bound was reached.
+func (m *BTreeMap[K, V]) descend(n *btreeNode[K, V], hi, lo *K, fn func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	i := len(n.entries)
+	if hi != nil {
+		i = m.upperBound(n, *hi)
+	}
+	if len(n.children) > 0 && !m.descend(n.children[i], hi, lo, fn) {
+		return false
+	}
+	for i--; i >= 0; i-- {
+		e := n.entries[i]
+		if lo != nil && !m.less(*lo, e.key) {
+			return false
+		}
+		if !fn(e.key, e.value) {
+			return false
+		}
+		if len(n.children) > 0 && !m.descend(n.children[i], hi, lo, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// min returns the smallest entry in the subtree rooted at n.
+func (n *btreeNode[K, V]) min() btreeEntry[K, V] {
+	for len(n.children) > 0 {
+		n = n.children[0]
+	}
+	return n.entries[0]
+}
+
+// max returns the largest entry in the subtree rooted at n.
+func (n *btreeNode[K, V]) max() btreeEntry[K, V] {
+	for len(n.children) > 0 {
+		n = n.children[len(n.children)-1]
+	}
+	return n.entries[len(n.entries)-1]
+}
+
+// resize recomputes the size of n from its entries and children.
+func (n *btreeNode[K, V]) resize() {
+	n.size = len(n.entries)
+	for _, c := range n.children {
+		n.size += c.size
+	}
+}
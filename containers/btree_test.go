@@ -0,0 +1,160 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[int]string) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func TestBTreeMap_MatchesMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	m := NewOrderedBTreeMap[int, string]()
+	want := map[int]string{}
+
+	for i := 0; i < 20000; i++ {
+		k := rng.Intn(2000)
+		if rng.Intn(3) == 0 {
+			_, existed := want[k]
+			delete(want, k)
+			if _, ok := m.Delete(k); ok != existed {
+				t.Fatalf("Delete(%v): expected ok=%v, got %v instead", k, existed, ok)
+			}
+			continue
+		}
+		_, existed := want[k]
+		want[k] = "v"
+		if inserted := m.Set(k, "v"); inserted == existed {
+			t.Fatalf("Set(%v): expected inserted=%v, got %v instead", k, !existed, inserted)
+		}
+	}
+
+	if m.Len() != len(want) {
+		t.Fatalf("expected length %v, got %v instead", len(want), m.Len())
+	}
+	keys := sortedKeys(want)
+	var got []int
+	m.Ascend(func(k int, _ string) bool {
+		got = append(got, k)
+		return true
+	})
+	if !reflect.DeepEqual(keys, got) {
+		t.Fatal("expected Ascend to visit keys in sorted order")
+	}
+	for i, k := range keys {
+		if r := m.Rank(k); r != i {
+			t.Fatalf("Rank(%v): expected %v, got %v instead", k, i, r)
+		}
+		if s, _, ok := m.Select(i); s != k || !ok {
+			t.Fatalf("Select(%v): expected %v, got %v instead with ok=%v", i, k, s, ok)
+		}
+	}
+	if _, _, ok := m.Select(len(keys)); ok {
+		t.Error("expected out of range Select to return false")
+	}
+	for _, k := range keys {
+		if _, ok := m.Delete(k); !ok {
+			t.Fatalf("failed to delete %v", k)
+		}
+	}
+	if m.Len() != 0 {
+		t.Errorf("expected empty map, got length %v", m.Len())
+	}
+}
+
+func TestBTreeMap_Bounds(t *testing.T) {
+	m := NewOrderedBTreeMap[int, int]()
+	if _, _, ok := m.Min(); ok {
+		t.Error("expected Min on empty map to return false")
+	}
+	for i := 0; i < 1000; i += 10 {
+		m.Set(i, i*2)
+	}
+	if k, v, ok := m.Min(); k != 0 || v != 0 || !ok {
+		t.Errorf("expected Min to return 0, got %v instead", k)
+	}
+	if k, v, ok := m.Max(); k != 990 || v != 1980 || !ok {
+		t.Errorf("expected Max to return 990, got %v instead", k)
+	}
+	if v, ok := m.Get(500); v != 1000 || !ok {
+		t.Errorf("expected Get(500) to return 1000, got %v instead", v)
+	}
+	if _, ok := m.Get(505); ok {
+		t.Error("expected Get of missing key to return false")
+	}
+	if k, _, _ := m.Floor(505); k != 500 {
+		t.Errorf("expected Floor(505) to return 500, got %v instead", k)
+	}
+	if k, _, _ := m.Floor(500); k != 500 {
+		t.Errorf("expected Floor(500) to return 500, got %v instead", k)
+	}
+	if _, _, ok := m.Floor(-1); ok {
+		t.Error("expected Floor below minimum to return false")
+	}
+	if k, _, _ := m.Ceiling(505); k != 510 {
+		t.Errorf("expected Ceiling(505) to return 510, got %v instead", k)
+	}
+	if _, _, ok := m.Ceiling(991); ok {
+		t.Error("expected Ceiling above maximum to return false")
+	}
+	if r := m.Rank(505); r != 51 {
+		t.Errorf("expected Rank(505) to return 51, got %v instead", r)
+	}
+}
+
+func TestBTreeMap_Ranges(t *testing.T) {
+	m := NewOrderedBTreeMap[int, struct{}]()
+	for i := 0; i < 500; i++ {
+		m.Set(i, struct{}{})
+	}
+	var asc []int
+	m.AscendRange(100, 105, func(k int, _ struct{}) bool {
+		asc = append(asc, k)
+		return true
+	})
+	if !reflect.DeepEqual(asc, []int{100, 101, 102, 103, 104}) {
+		t.Errorf("unexpected AscendRange result %v", asc)
+	}
+	var desc []int
+	m.DescendRange(105, 100, func(k int, _ struct{}) bool {
+		desc = append(desc, k)
+		return true
+	})
+	if !reflect.DeepEqual(desc, []int{105, 104, 103, 102, 101}) {
+		t.Errorf("unexpected DescendRange result %v", desc)
+	}
+	var stopped []int
+	m.Descend(func(k int, _ struct{}) bool {
+		stopped = append(stopped, k)
+		return len(stopped) < 3
+	})
+	if !reflect.DeepEqual(stopped, []int{499, 498, 497}) {
+		t.Errorf("unexpected Descend result %v", stopped)
+	}
+}
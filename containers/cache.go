@@ -0,0 +1,64 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"time"
+)
+
+// CacheStats holds the counters of a cache.
+type CacheStats struct {
+	// Hits is the number of Get calls that found a live entry.
+	Hits uint64
+	// Misses is the number of Get calls that did not find a live entry.
+	Misses uint64
+	// Evictions is the number of entries removed to make room for new entries
+	// or because their TTL expired.
+	Evictions uint64
+}
+
+// cacheEntry is a key value pair stored in a cache, with an optional expiry.
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// expired reports whether the entry has a TTL that has elapsed at now.
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// expiry returns the time an entry put at now with ttl expires, or the zero
+// time if ttl is not positive.
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// evicted calls onEvict for every entry in entries. Callbacks are run after
+// the cache lock has been released so they may safely use the cache.
+func evicted[K comparable, V any](onEvict func(K, V), entries []*cacheEntry[K, V]) {
+	if onEvict == nil {
+		return
+	}
+	for _, e := range entries {
+		onEvict(e.key, e.value)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"sync"
+)
+
+// minDequeBuffer is the smallest ring buffer a Deque allocates once a value
+// has been pushed into it.
+const minDequeBuffer = 8
+
+// Deque is a double-ended queue backed by a growable ring buffer that is
+// thread safe. Values can be pushed and popped from both ends in amortized
+// constant time.
+type Deque[T any] struct {
+	mu       sync.RWMutex
+	capacity int
+	values   []T
+	head     int
+	size     int
+}
+
+// NewDeque creates an empty deque. If capacity is greater than zero, the deque
+// will not grow beyond capacity values and pushes will fail once it is full.
+// A capacity of zero or less creates an unbounded deque.
+func NewDeque[T any](capacity int) *Deque[T] {
+	initial := minDequeBuffer
+	if capacity > 0 && capacity < initial {
+		initial = capacity
+	}
+	return &Deque[T]{
+		mu:       sync.RWMutex{},
+		values:   make([]T, initial),
+		capacity: capacity,
+		head:     0,
+		size:     0,
+	}
+}
+
+// PushFront adds an item to the front of the deque. If the deque has reached
+// its capacity, it will return false.
+func (d *Deque[T]) PushFront(item T) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.grow() {
+		return false
+	}
+	d.head = d.index(-1)
+	d.values[d.head] = item
+	d.size++
+	return true
+}
+
+// PushBack adds an item to the back of the deque. If the deque has reached
+// its capacity, it will return false.
+func (d *Deque[T]) PushBack(item T) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.grow() {
+		return false
+	}
+	d.values[d.index(d.size)] = item
+	d.size++
+	return true
+}
+
+// PopFront removes and returns the value at the front of the deque. If the
+// deque is empty, it will return the "default" value of type T, and false.
+func (d *Deque[T]) PopFront() (T, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var empty T
+	if d.size == 0 {
+		return empty, false
+	}
+	v := d.values[d.head]
+	d.values[d.head] = empty
+	d.head = d.index(1)
+	d.size--
+	return v, true
+}
+
+// PopBack removes and returns the value at the back of the deque. If the
+// deque is empty, it will return the "default" value of type T, and false.
+func (d *Deque[T]) PopBack() (T, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var empty T
+	if d.size == 0 {
+		return empty, false
+	}
+	i := d.index(d.size - 1)
+	v := d.values[i]
+	d.values[i] = empty
+	d.size--
+	return v, true
+}
+
+// Front returns the value at the front of the deque without removing it. If
+// the deque is empty, it will return the "default" value of type T, and false.
+func (d *Deque[T]) Front() (T, bool) {
+	return d.At(0)
+}
+
+// Back returns the value at the back of the deque without removing it. If
+// the deque is empty, it will return the "default" value of type T, and false.
+func (d *Deque[T]) Back() (T, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.size == 0 {
+		var empty T
+		return empty, false
+	}
+	return d.values[d.index(d.size-1)], true
+}
+
+// At returns the value at position i, where 0 is the front of the deque. If
+// i is out of range, it will return the "default" value of type T, and false.
+func (d *Deque[T]) At(i int) (T, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if i < 0 || i >= d.size {
+		var empty T
+		return empty, false
+	}
+	return d.values[d.index(i)], true
+}
+
+// IsEmpty returns true when the deque does not contain any values.
+func (d *Deque[T]) IsEmpty() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.size == 0
+}
+
+// IsFull returns true when a bounded deque has reached full capacity.
+// An unbounded deque is never full.
+func (d *Deque[T]) IsFull() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.full()
+}
+
+// Size returns the current size of the deque.
+func (d *Deque[T]) Size() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.size
+}
+
+// full reports whether the deque has reached its capacity. The caller must
+// hold the lock.
+func (d *Deque[T]) full() bool {
+	return d.capacity > 0 && d.size >= d.capacity
+}
+
+// index maps the logical position i, relative to the head, onto the ring
+// buffer. The caller must hold the lock.
+func (d *Deque[T]) index(i int) int {
+	n := len(d.values)
+	return ((d.head+i)%n + n) % n
+}
+
+// grow makes room for one more value, reallocating the ring buffer if it is
+// exhausted. It returns false if the deque is at capacity. The caller must
+// hold the lock.
+func (d *Deque[T]) grow() bool {
+	if d.full() {
+		return false
+	}
+	if d.size < len(d.values) {
+		return true
+	}
+	n := len(d.values) * 2
+	if n < minDequeBuffer {
+		n = minDequeBuffer
+	}
+	if d.capacity > 0 && n > d.capacity {
+		n = d.capacity
+	}
+	values := make([]T, n)
+	for i := 0; i < d.size; i++ {
+		values[i] = d.values[d.index(i)]
+	}
+	d.values = values
+	d.head = 0
+	return true
+}
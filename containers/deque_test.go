@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"testing"
+)
+
+func TestDeque_PushPop(t *testing.T) {
+	d := NewDeque[int](0)
+	if _, ok := d.Front(); ok {
+		t.Errorf("expected deque.Front on empty deque to return false, got %v instead", ok)
+	}
+	for i := 1; i <= 20; i++ {
+		if !d.PushBack(i) {
+			t.Errorf("failed to push %v to back of unbounded deque", i)
+		}
+	}
+	for i := 0; i >= -20; i-- {
+		if !d.PushFront(i) {
+			t.Errorf("failed to push %v to front of unbounded deque", i)
+		}
+	}
+	if d.Size() != 41 || d.IsFull() {
+		t.Errorf("expected unbounded deque of size 41, got size %v with IsFull = %v", d.Size(), d.IsFull())
+	}
+	for i := 0; i < d.Size(); i++ {
+		v, ok := d.At(i)
+		if v != i-20 || !ok {
+			t.Errorf("expected At(%v) to return %v, got %v instead with ok=%v", i, i-20, v, ok)
+		}
+	}
+	if _, ok := d.At(41); ok {
+		t.Errorf("expected out of range At to return false, got %v instead", ok)
+	}
+	if v, ok := d.Front(); v != -20 || !ok {
+		t.Errorf("expected front -20, got %v instead with ok=%v", v, ok)
+	}
+	if v, ok := d.Back(); v != 20 || !ok {
+		t.Errorf("expected back 20, got %v instead with ok=%v", v, ok)
+	}
+	for i := -20; i <= 0; i++ {
+		v, ok := d.PopFront()
+		if v != i || !ok {
+			t.Errorf("expected %v got %v instead with ok=%v", i, v, ok)
+		}
+	}
+	for i := 20; i > 0; i-- {
+		v, ok := d.PopBack()
+		if v != i || !ok {
+			t.Errorf("expected %v got %v instead with ok=%v", i, v, ok)
+		}
+	}
+	if _, ok := d.PopBack(); ok || !d.IsEmpty() {
+		t.Errorf("expected empty deque, got size %v", d.Size())
+	}
+}
+
+func TestDeque_Capacity(t *testing.T) {
+	d := NewDeque[string](3)
+	for _, v := range []string{"a", "b", "c"} {
+		if !d.PushBack(v) {
+			t.Errorf("failed to push %v to deque", v)
+		}
+	}
+	if !d.IsFull() {
+		t.Errorf("expected deque of capacity 3 to be full, have size %v", d.Size())
+	}
+	if d.PushFront("z") || d.PushBack("z") {
+		t.Error("expected push onto full deque to return false")
+	}
+	if v, ok := d.PopFront(); v != "a" || !ok {
+		t.Errorf("expected a, got %v instead with ok=%v", v, ok)
+	}
+	if !d.PushBack("d") {
+		t.Error("failed to push after making room in deque")
+	}
+	want := []string{"b", "c", "d"}
+	for i, w := range want {
+		if v, _ := d.At(i); v != w {
+			t.Errorf("expected At(%v) to return %v, got %v instead", i, w, v)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lfuItem tracks a cache entry together with its access frequency and its
+// position in the list of entries sharing that frequency.
+type lfuItem[K comparable, V any] struct {
+	entry *cacheEntry[K, V]
+	freq  int
+	el    *list.Element
+}
+
+// LFU is a thread safe fixed capacity cache that evicts the least frequently
+// used entry when it is full, choosing the least recently used entry among
+// those with equal frequency. Entries may be given a TTL, after which they are
+// treated as missing and removed the next time they are looked up.
+type LFU[K comparable, V any] struct {
+	mu       sync.RWMutex
+	capacity int
+	onEvict  func(K, V)
+	items    map[K]*lfuItem[K, V]
+	freqs    map[int]*list.List
+	minFreq  int
+	stats    CacheStats
+	now      func() time.Time
+}
+
+// NewLFU creates an empty LFU cache that holds at most capacity entries.
+// onEvict, if not nil, is called with every entry that is evicted or expires.
+// NewLFU panics if capacity is not positive.
+func NewLFU[K comparable, V any](capacity int, onEvict func(key K, value V)) *LFU[K, V] {
+	if capacity <= 0 {
+		panic("containers: LFU capacity must be positive")
+	}
+	return &LFU[K, V]{
+		mu:       sync.RWMutex{},
+		capacity: capacity,
+		onEvict:  onEvict,
+		items:    make(map[K]*lfuItem[K, V], capacity),
+		freqs:    make(map[int]*list.List),
+		minFreq:  0,
+		now:      time.Now,
+	}
+}
+
+// Get returns the value stored for key and increments its use frequency. If
+// key is not in the cache or has expired, it will return the "default" value
+// of type V, and false.
+func (c *LFU[K, V]) Get(key K) (V, bool) {
+	var (
+		value V
+		out   []*cacheEntry[K, V]
+	)
+	c.mu.Lock()
+	it, ok := c.items[key]
+	if ok {
+		if it.entry.expired(c.now()) {
+			out = append(out, c.remove(it))
+			c.stats.Evictions++
+			ok = false
+		} else {
+			value = it.entry.value
+			c.touch(it)
+		}
+	}
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+	evicted(c.onEvict, out)
+	return value, ok
+}
+
+// Peek returns the value stored for key without changing its use frequency or
+// updating the cache statistics. If key is not in the cache or has expired,
+// it will return the "default" value of type V, and false.
+func (c *LFU[K, V]) Peek(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if it, ok := c.items[key]; ok && !it.entry.expired(c.now()) {
+		return it.entry.value, true
+	}
+	var empty V
+	return empty, false
+}
+
+// Put stores value for key, evicting the least frequently used entry if the
+// cache is full. Replacing the value of an existing key counts as a use.
+func (c *LFU[K, V]) Put(key K, value V) {
+	c.PutWithTTL(key, value, 0)
+}
+
+// PutWithTTL stores value for key like Put, and expires it once ttl has
+// elapsed. A ttl that is not positive never expires.
+func (c *LFU[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	now := c.now()
+	if it, ok := c.items[key]; ok {
+		it.entry.value = value
+		it.entry.expires = expiry(now, ttl)
+		c.touch(it)
+		c.mu.Unlock()
+		return
+	}
+	var out []*cacheEntry[K, V]
+	if len(c.items) >= c.capacity {
+		out = append(out, c.remove(c.victim()))
+		c.stats.Evictions++
+	}
+	it := &lfuItem[K, V]{
+		entry: &cacheEntry[K, V]{
+			key:     key,
+			value:   value,
+			expires: expiry(now, ttl),
+		},
+		freq: 1,
+	}
+	it.el = c.bucket(1).PushFront(it)
+	c.items[key] = it
+	c.minFreq = 1
+	c.mu.Unlock()
+	evicted(c.onEvict, out)
+}
+
+// Delete removes key from the cache without calling the eviction callback.
+// It returns true if key was in the cache.
+func (c *LFU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	it, ok := c.items[key]
+	if ok {
+		c.remove(it)
+	}
+	return ok
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// that have not been looked up yet.
+func (c *LFU[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *LFU[K, V]) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats
+}
+
+// bucket returns the list of items used freq times, creating it if needed.
+// The caller must hold the lock.
+func (c *LFU[K, V]) bucket(freq int) *list.List {
+	l, ok := c.freqs[freq]
+	if !ok {
+		l = list.New()
+		c.freqs[freq] = l
+	}
+	return l
+}
+
+// unlink takes it out of its frequency list. The caller must hold the lock.
+func (c *LFU[K, V]) unlink(it *lfuItem[K, V]) {
+	l := c.freqs[it.freq]
+	l.Remove(it.el)
+	if l.Len() == 0 {
+		delete(c.freqs, it.freq)
+	}
+}
+
+// touch increments the use frequency of it. The caller must hold the lock.
+func (c *LFU[K, V]) touch(it *lfuItem[K, V]) {
+	c.unlink(it)
+	if _, ok := c.freqs[it.freq]; !ok && c.minFreq == it.freq {
+		c.minFreq++
+	}
+	it.freq++
+	it.el = c.bucket(it.freq).PushFront(it)
+}
+
+// victim returns the least recently used item among the least frequently
+// used items. The cache must not be empty. The caller must hold the lock.
+func (c *LFU[K, V]) victim() *lfuItem[K, V] {
+	if _, ok := c.freqs[c.minFreq]; !ok {
+		// Delete and expiry can empty the minimum frequency list without
+		// knowing the next smallest frequency, so it is recomputed here.
+		c.minFreq = 0
+		for f := range c.freqs {
+			if c.minFreq == 0 || f < c.minFreq {
+				c.minFreq = f
+			}
+		}
+	}
+	return c.freqs[c.minFreq].Back().Value.(*lfuItem[K, V])
+}
+
+// remove takes it out of the cache and returns its entry. The caller must
+// hold the lock.
+func (c *LFU[K, V]) remove(it *lfuItem[K, V]) *cacheEntry[K, V] {
+	c.unlink(it)
+	delete(c.items, it.entry.key)
+	return it.entry
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLFU_Eviction(t *testing.T) {
+	var evictedKeys []string
+	c := NewLFU(3, func(k string, _ int) {
+		evictedKeys = append(evictedKeys, k)
+	})
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("c")
+	c.Put("d", 4)
+	if _, ok := c.Peek("b"); ok {
+		t.Error("expected least frequently used key b to be evicted")
+	}
+	c.Put("e", 5)
+	if _, ok := c.Peek("d"); ok {
+		t.Error("expected key d with a single use to be evicted")
+	}
+	for _, k := range []string{"a", "c", "e"} {
+		if _, ok := c.Peek(k); !ok {
+			t.Errorf("expected key %v to remain in the cache", k)
+		}
+	}
+	if len(evictedKeys) != 2 || evictedKeys[0] != "b" || evictedKeys[1] != "d" {
+		t.Errorf("expected eviction callbacks for b and d, got %v instead", evictedKeys)
+	}
+	stats := c.Stats()
+	if stats.Hits != 3 || stats.Misses != 0 || stats.Evictions != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLFU_DeleteResetsMinimumFrequency(t *testing.T) {
+	c := NewLFU[int, int](2, nil)
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Get(2)
+	c.Get(2)
+	if !c.Delete(1) {
+		t.Error("expected Delete to succeed")
+	}
+	c.Put(3, 3)
+	c.Get(3)
+	c.Put(4, 4)
+	if _, ok := c.Peek(3); ok {
+		t.Error("expected key 3 to be evicted as the least frequently used")
+	}
+	if _, ok := c.Peek(2); !ok {
+		t.Error("expected key 2 to remain in the cache")
+	}
+}
+
+func TestLFU_TTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewLFU[string, int](2, nil)
+	c.now = func() time.Time { return now }
+
+	c.PutWithTTL("a", 1, time.Minute)
+	if v, ok := c.Get("a"); v != 1 || !ok {
+		t.Errorf("expected live key to be returned, got %v with ok=%v", v, ok)
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected Get of expired key to return false")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected expired key to be removed, got length %v", c.Len())
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Evictions != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
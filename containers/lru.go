@@ -0,0 +1,166 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a thread safe fixed capacity cache that evicts the least recently
+// used entry when it is full. Entries may be given a TTL, after which they are
+// treated as missing and removed the next time they are looked up.
+type LRU[K comparable, V any] struct {
+	mu       sync.RWMutex
+	capacity int
+	onEvict  func(K, V)
+	items    map[K]*list.Element
+	order    *list.List
+	stats    CacheStats
+	now      func() time.Time
+}
+
+// NewLRU creates an empty LRU cache that holds at most capacity entries.
+// onEvict, if not nil, is called with every entry that is evicted or expires.
+// NewLRU panics if capacity is not positive.
+func NewLRU[K comparable, V any](capacity int, onEvict func(key K, value V)) *LRU[K, V] {
+	if capacity <= 0 {
+		panic("containers: LRU capacity must be positive")
+	}
+	return &LRU[K, V]{
+		mu:       sync.RWMutex{},
+		capacity: capacity,
+		onEvict:  onEvict,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value stored for key and marks it as the most recently
+// used entry. If key is not in the cache or has expired, it will return the
+// "default" value of type V, and false.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	var (
+		value V
+		out   []*cacheEntry[K, V]
+	)
+	c.mu.Lock()
+	el, ok := c.items[key]
+	if ok {
+		if e := el.Value.(*cacheEntry[K, V]); e.expired(c.now()) {
+			out = append(out, c.remove(el))
+			c.stats.Evictions++
+			ok = false
+		} else {
+			value = e.value
+			c.order.MoveToFront(el)
+		}
+	}
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+	evicted(c.onEvict, out)
+	return value, ok
+}
+
+// Peek returns the value stored for key without marking it as used or
+// updating the cache statistics. If key is not in the cache or has expired,
+// it will return the "default" value of type V, and false.
+func (c *LRU[K, V]) Peek(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if el, ok := c.items[key]; ok {
+		if e := el.Value.(*cacheEntry[K, V]); !e.expired(c.now()) {
+			return e.value, true
+		}
+	}
+	var empty V
+	return empty, false
+}
+
+// Put stores value for key as the most recently used entry, evicting the
+// least recently used entry if the cache is full.
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.PutWithTTL(key, value, 0)
+}
+
+// PutWithTTL stores value for key like Put, and expires it once ttl has
+// elapsed. A ttl that is not positive never expires.
+func (c *LRU[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	now := c.now()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*cacheEntry[K, V])
+		e.value = value
+		e.expires = expiry(now, ttl)
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return
+	}
+	var out []*cacheEntry[K, V]
+	if c.order.Len() >= c.capacity {
+		out = append(out, c.remove(c.order.Back()))
+		c.stats.Evictions++
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry[K, V]{
+		key:     key,
+		value:   value,
+		expires: expiry(now, ttl),
+	})
+	c.mu.Unlock()
+	evicted(c.onEvict, out)
+}
+
+// Delete removes key from the cache without calling the eviction callback.
+// It returns true if key was in the cache.
+func (c *LRU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if ok {
+		c.remove(el)
+	}
+	return ok
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// that have not been looked up yet.
+func (c *LRU[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.order.Len()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *LRU[K, V]) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats
+}
+
+// remove takes el out of the cache and returns its entry. The caller must
+// hold the lock.
+func (c *LRU[K, V]) remove(el *list.Element) *cacheEntry[K, V] {
+	e := c.order.Remove(el).(*cacheEntry[K, V])
+	delete(c.items, e.key)
+	return e
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_Eviction(t *testing.T) {
+	var evictedKeys []string
+	c := NewLRU(2, func(k string, _ int) {
+		evictedKeys = append(evictedKeys, k)
+	})
+	c.Put("a", 1)
+	c.Put("b", 2)
+	if v, ok := c.Get("a"); v != 1 || !ok {
+		t.Errorf("expected Get(a) to return 1, got %v instead with ok=%v", v, ok)
+	}
+	c.Put("c", 3)
+	if _, ok := c.Peek("b"); ok {
+		t.Error("expected least recently used key b to be evicted")
+	}
+	if len(evictedKeys) != 1 || evictedKeys[0] != "b" {
+		t.Errorf("expected eviction callback for b, got %v instead", evictedKeys)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected Get of evicted key to return false")
+	}
+	if !c.Delete("a") || c.Delete("a") {
+		t.Error("expected Delete to only succeed once")
+	}
+	if len(evictedKeys) != 1 {
+		t.Error("expected Delete to not call the eviction callback")
+	}
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected length 1, got %v instead", c.Len())
+	}
+}
+
+func TestLRU_PeekDoesNotPromote(t *testing.T) {
+	c := NewLRU[int, int](2, nil)
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Peek(1)
+	c.Put(3, 3)
+	if _, ok := c.Peek(1); ok {
+		t.Error("expected Peek to not mark key 1 as recently used")
+	}
+	if s := c.Stats(); s.Hits != 0 || s.Misses != 0 {
+		t.Errorf("expected Peek to not update stats, got %+v", s)
+	}
+}
+
+func TestLRU_TTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	var expired []int
+	c := NewLRU(10, func(k int, _ string) {
+		expired = append(expired, k)
+	})
+	c.now = func() time.Time { return now }
+
+	c.PutWithTTL(1, "short", time.Second)
+	c.Put(2, "forever")
+	now = now.Add(2 * time.Second)
+
+	if _, ok := c.Peek(1); ok {
+		t.Error("expected Peek of expired key to return false")
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected expired key to be removed lazily, got length %v", c.Len())
+	}
+	if _, ok := c.Get(1); ok {
+		t.Error("expected Get of expired key to return false")
+	}
+	if v, ok := c.Get(2); v != "forever" || !ok {
+		t.Errorf("expected key without TTL to remain, got %v with ok=%v", v, ok)
+	}
+	if len(expired) != 1 || expired[0] != 1 || c.Len() != 1 {
+		t.Errorf("expected key 1 to be expired, got %v with length %v", expired, c.Len())
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"sync"
+
+	"github.com/bradleybonitatibus/rig/algorithm"
+)
+
+// Handle references a value that has been pushed into a PriorityQueue. It can
+// be used to update or remove the value after it has been pushed.
+type Handle[T any] struct {
+	value T
+	index int
+}
+
+// Value returns the value referenced by the handle.
+func (h *Handle[T]) Value() T {
+	return h.value
+}
+
+// PriorityQueue is a thread safe priority queue backed by a binary heap. The
+// value at the top of the queue is the one for which less reports true against
+// every other value, so a less of `a < b` yields a min-heap.
+type PriorityQueue[T any] struct {
+	mu    sync.RWMutex
+	less  func(a, b T) bool
+	items []*Handle[T]
+}
+
+// NewPriorityQueue creates an empty priority queue ordered by less.
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{
+		mu:    sync.RWMutex{},
+		less:  less,
+		items: make([]*Handle[T], 0),
+	}
+}
+
+// NewOrderedPriorityQueue creates an empty min priority queue for ordered
+// types.
+func NewOrderedPriorityQueue[T algorithm.Ordered]() *PriorityQueue[T] {
+	return NewPriorityQueue(func(a, b T) bool {
+		return a < b
+	})
+}
+
+// NewPriorityQueueFromSlice creates a priority queue ordered by less that
+// contains all of values. The heap is built in O(n) time, and values is not
+// modified.
+func NewPriorityQueueFromSlice[T any](values []T, less func(a, b T) bool) *PriorityQueue[T] {
+	pq := NewPriorityQueue(less)
+	pq.items = make([]*Handle[T], len(values))
+	for i, v := range values {
+		pq.items[i] = &Handle[T]{value: v, index: i}
+	}
+	for i := len(pq.items)/2 - 1; i >= 0; i-- {
+		pq.down(i)
+	}
+	return pq
+}
+
+// Push adds an item to the queue and returns a handle that references it.
+func (pq *PriorityQueue[T]) Push(item T) *Handle[T] {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	h := &Handle[T]{value: item, index: len(pq.items)}
+	pq.items = append(pq.items, h)
+	pq.up(h.index)
+	return h
+}
+
+// Pop removes and returns the value at the top of the queue. If the queue is
+// empty, it will return the "default" value of type T, and false.
+func (pq *PriorityQueue[T]) Pop() (T, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if len(pq.items) == 0 {
+		var empty T
+		return empty, false
+	}
+	return pq.remove(0), true
+}
+
+// Peek returns the value at the top of the queue. If the queue is empty,
+// it will return the "default" value of type T, and false.
+func (pq *PriorityQueue[T]) Peek() (T, bool) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	if len(pq.items) == 0 {
+		var empty T
+		return empty, false
+	}
+	return pq.items[0].value, true
+}
+
+// Len returns the number of values in the queue.
+func (pq *PriorityQueue[T]) Len() int {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	return len(pq.items)
+}
+
+// Fix re-establishes the heap ordering after the value referenced by h has
+// changed its priority, for example when T is a pointer that was mutated.
+// It returns false if h is no longer in the queue.
+func (pq *PriorityQueue[T]) Fix(h *Handle[T]) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if !pq.owns(h) {
+		return false
+	}
+	pq.fix(h.index)
+	return true
+}
+
+// Update replaces the value referenced by h with item and re-establishes the
+// heap ordering. It returns false if h is no longer in the queue.
+func (pq *PriorityQueue[T]) Update(h *Handle[T], item T) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if !pq.owns(h) {
+		return false
+	}
+	h.value = item
+	pq.fix(h.index)
+	return true
+}
+
+// Remove removes the value referenced by h from the queue and returns it.
+// If h is no longer in the queue, it will return the "default" value of type
+// T, and false.
+func (pq *PriorityQueue[T]) Remove(h *Handle[T]) (T, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if !pq.owns(h) {
+		var empty T
+		return empty, false
+	}
+	return pq.remove(h.index), true
+}
+
+// owns reports whether h references a value in this queue. The caller must
+// hold the lock.
+func (pq *PriorityQueue[T]) owns(h *Handle[T]) bool {
+	return h != nil && h.index >= 0 && h.index < len(pq.items) && pq.items[h.index] == h
+}
+
+// remove takes the value at index i out of the heap. The caller must hold
+// the lock.
+func (pq *PriorityQueue[T]) remove(i int) T {
+	h := pq.items[i]
+	last := len(pq.items) - 1
+	if i != last {
+		pq.swap(i, last)
+	}
+	pq.items[last] = nil
+	pq.items = pq.items[:last]
+	if i != last {
+		pq.fix(i)
+	}
+	h.index = -1
+	return h.value
+}
+
+// fix moves the value at index i up or down until the heap is ordered.
+func (pq *PriorityQueue[T]) fix(i int) {
+	if !pq.down(i) {
+		pq.up(i)
+	}
+}
+
+// up moves the value at index i towards the root of the heap.
+func (pq *PriorityQueue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !pq.less(pq.items[i].value, pq.items[parent].value) {
+			break
+		}
+		pq.swap(i, parent)
+		i = parent
+	}
+}
+
+// down moves the value at index i towards the leaves of the heap, and
+// reports whether it was moved.
+func (pq *PriorityQueue[T]) down(i int) bool {
+	start := i
+	n := len(pq.items)
+	for {
+		left := 2*i + 1
+		if left >= n {
+			break
+		}
+		child := left
+		if right := left + 1; right < n && pq.less(pq.items[right].value, pq.items[left].value) {
+			child = right
+		}
+		if !pq.less(pq.items[child].value, pq.items[i].value) {
+			break
+		}
+		pq.swap(i, child)
+		i = child
+	}
+	return i > start
+}
+
+// swap exchanges the values at index i and j, keeping their handles in sync.
+func (pq *PriorityQueue[T]) swap(i, j int) {
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
+}
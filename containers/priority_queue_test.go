@@ -0,0 +1,107 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestPriorityQueue_PushPop(t *testing.T) {
+	pq := NewOrderedPriorityQueue[int]()
+	if _, ok := pq.Peek(); ok {
+		t.Errorf("expected Peek on empty queue to return false, got %v instead", ok)
+	}
+	values := rand.Perm(100)
+	for _, v := range values {
+		pq.Push(v)
+	}
+	if pq.Len() != 100 {
+		t.Errorf("expected queue of length 100, got %v instead", pq.Len())
+	}
+	if v, ok := pq.Peek(); v != 0 || !ok {
+		t.Errorf("expected Peek to return 0, got %v instead with ok=%v", v, ok)
+	}
+	for i := 0; i < 100; i++ {
+		v, ok := pq.Pop()
+		if v != i || !ok {
+			t.Errorf("expected %v got %v instead with ok=%v", i, v, ok)
+		}
+	}
+	if _, ok := pq.Pop(); ok {
+		t.Errorf("expected false got %v instead", ok)
+	}
+}
+
+func TestPriorityQueue_FromSlice(t *testing.T) {
+	values := []string{"pear", "apple", "fig", "kiwi", "banana", "cherry"}
+	pq := NewPriorityQueueFromSlice(values, func(a, b string) bool {
+		return a > b
+	})
+	want := append([]string{}, values...)
+	sort.Sort(sort.Reverse(sort.StringSlice(want)))
+	for _, w := range want {
+		if v, _ := pq.Pop(); v != w {
+			t.Errorf("expected %v got %v instead", w, v)
+		}
+	}
+	if values[0] != "pear" {
+		t.Error("expected NewPriorityQueueFromSlice to not modify values")
+	}
+}
+
+func TestPriorityQueue_Handles(t *testing.T) {
+	type job struct {
+		name     string
+		priority int
+	}
+	pq := NewPriorityQueue(func(a, b *job) bool {
+		return a.priority < b.priority
+	})
+	a := pq.Push(&job{name: "a", priority: 3})
+	b := pq.Push(&job{name: "b", priority: 2})
+	c := pq.Push(&job{name: "c", priority: 1})
+
+	a.Value().priority = 0
+	if !pq.Fix(a) {
+		t.Error("expected Fix to succeed for handle in queue")
+	}
+	if v, _ := pq.Peek(); v.name != "a" {
+		t.Errorf("expected a at top after Fix, got %v instead", v.name)
+	}
+	if !pq.Update(b, &job{name: "b", priority: -1}) {
+		t.Error("expected Update to succeed for handle in queue")
+	}
+	if v, _ := pq.Peek(); v.name != "b" {
+		t.Errorf("expected b at top after Update, got %v instead", v.name)
+	}
+	if v, ok := pq.Remove(c); v.name != "c" || !ok {
+		t.Errorf("expected to remove c, got %v instead with ok=%v", v.name, ok)
+	}
+	if _, ok := pq.Remove(c); ok {
+		t.Error("expected Remove of removed handle to return false")
+	}
+	if pq.Fix(c) || pq.Update(c, &job{}) {
+		t.Error("expected Fix and Update of removed handle to return false")
+	}
+	for _, want := range []string{"b", "a"} {
+		if v, _ := pq.Pop(); v.name != want {
+			t.Errorf("expected %v got %v instead", want, v.name)
+		}
+	}
+}
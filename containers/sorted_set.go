@@ -0,0 +1,141 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"github.com/bradleybonitatibus/rig/algorithm"
+)
+
+// SortedSet is a thread safe set that keeps its keys in sorted order, backed
+// by a BTreeMap.
+type SortedSet[K any] struct {
+	m *BTreeMap[K, struct{}]
+}
+
+// NewSortedSet creates an empty set ordered by less.
+func NewSortedSet[K any](less func(a, b K) bool) *SortedSet[K] {
+	return &SortedSet[K]{
+		m: NewBTreeMap[K, struct{}](less),
+	}
+}
+
+// NewOrderedSortedSet creates an empty set for ordered key types, sorted in
+// ascending order.
+func NewOrderedSortedSet[K algorithm.Ordered]() *SortedSet[K] {
+	return &SortedSet[K]{
+		m: NewOrderedBTreeMap[K, struct{}](),
+	}
+}
+
+// Add inserts key into the set. It returns true if key was not previously in
+// the set.
+func (s *SortedSet[K]) Add(key K) bool {
+	return s.m.Set(key, struct{}{})
+}
+
+// Remove deletes key from the set. It returns true if key was in the set.
+func (s *SortedSet[K]) Remove(key K) bool {
+	_, ok := s.m.Delete(key)
+	return ok
+}
+
+// Contains returns true if key is in the set.
+func (s *SortedSet[K]) Contains(key K) bool {
+	_, ok := s.m.Get(key)
+	return ok
+}
+
+// Len returns the number of keys in the set.
+func (s *SortedSet[K]) Len() int {
+	return s.m.Len()
+}
+
+// Min returns the smallest key in the set. If the set is empty, it will
+// return the "default" value of type K, and false.
+func (s *SortedSet[K]) Min() (K, bool) {
+	k, _, ok := s.m.Min()
+	return k, ok
+}
+
+// Max returns the largest key in the set. If the set is empty, it will
+// return the "default" value of type K, and false.
+func (s *SortedSet[K]) Max() (K, bool) {
+	k, _, ok := s.m.Max()
+	return k, ok
+}
+
+// Floor returns the largest key in the set that is less than or equal to key.
+// If there is no such key, it will return the "default" value of type K, and
+// false.
+func (s *SortedSet[K]) Floor(key K) (K, bool) {
+	k, _, ok := s.m.Floor(key)
+	return k, ok
+}
+
+// Ceiling returns the smallest key in the set that is greater than or equal
+// to key. If there is no such key, it will return the "default" value of type
+// K, and false.
+func (s *SortedSet[K]) Ceiling(key K) (K, bool) {
+	k, _, ok := s.m.Ceiling(key)
+	return k, ok
+}
+
+// Rank returns the number of keys in the set that are strictly less than key.
+func (s *SortedSet[K]) Rank(key K) int {
+	return s.m.Rank(key)
+}
+
+// Select returns the key at the zero-based index i in ascending order. If i
+// is out of range, it will return the "default" value of type K, and false.
+func (s *SortedSet[K]) Select(i int) (K, bool) {
+	k, _, ok := s.m.Select(i)
+	return k, ok
+}
+
+// Ascend calls fn for every key in ascending order until fn returns false.
+// fn must not modify the set.
+func (s *SortedSet[K]) Ascend(fn func(key K) bool) {
+	s.m.Ascend(func(k K, _ struct{}) bool {
+		return fn(k)
+	})
+}
+
+// AscendRange calls fn in ascending order for every key in the range
+// [greaterOrEqual, lessThan), until fn returns false. fn must not modify the
+// set.
+func (s *SortedSet[K]) AscendRange(greaterOrEqual, lessThan K, fn func(key K) bool) {
+	s.m.AscendRange(greaterOrEqual, lessThan, func(k K, _ struct{}) bool {
+		return fn(k)
+	})
+}
+
+// Descend calls fn for every key in descending order until fn returns false.
+// fn must not modify the set.
+func (s *SortedSet[K]) Descend(fn func(key K) bool) {
+	s.m.Descend(func(k K, _ struct{}) bool {
+		return fn(k)
+	})
+}
+
+// DescendRange calls fn in descending order for every key in the range
+// (greaterThan, lessOrEqual], until fn returns false. fn must not modify the
+// set.
+func (s *SortedSet[K]) DescendRange(lessOrEqual, greaterThan K, fn func(key K) bool) {
+	s.m.DescendRange(lessOrEqual, greaterThan, func(k K, _ struct{}) bool {
+		return fn(k)
+	})
+}
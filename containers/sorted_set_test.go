@@ -0,0 +1,74 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortedSet(t *testing.T) {
+	s := NewSortedSet(func(a, b string) bool {
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+	for _, v := range []string{"pear", "Apple", "fig", "kiwi", "banana"} {
+		if !s.Add(v) {
+			t.Errorf("expected Add(%v) to return true", v)
+		}
+	}
+	if s.Add("APPLE") {
+		t.Error("expected Add of an equal key to return false")
+	}
+	if !s.Contains("FIG") || s.Len() != 5 {
+		t.Errorf("expected set of length 5 containing fig, got length %v", s.Len())
+	}
+	if v, _ := s.Min(); v != "Apple" {
+		t.Errorf("expected Min to return Apple, got %v instead", v)
+	}
+	if v, _ := s.Max(); v != "pear" {
+		t.Errorf("expected Max to return pear, got %v instead", v)
+	}
+	if v, _ := s.Ceiling("c"); v != "fig" {
+		t.Errorf("expected Ceiling(c) to return fig, got %v instead", v)
+	}
+	if v, _ := s.Floor("c"); v != "banana" {
+		t.Errorf("expected Floor(c) to return banana, got %v instead", v)
+	}
+	if v, _ := s.Select(s.Rank("kiwi")); v != "kiwi" {
+		t.Errorf("expected Select(Rank(kiwi)) to return kiwi, got %v instead", v)
+	}
+	var got []string
+	s.AscendRange("b", "l", func(k string) bool {
+		got = append(got, k)
+		return true
+	})
+	if !reflect.DeepEqual(got, []string{"banana", "fig", "kiwi"}) {
+		t.Errorf("unexpected AscendRange result %v", got)
+	}
+	if !s.Remove("kiwi") || s.Remove("kiwi") {
+		t.Error("expected Remove to only succeed once")
+	}
+	got = got[:0]
+	s.Descend(func(k string) bool {
+		got = append(got, k)
+		return true
+	})
+	if !reflect.DeepEqual(got, []string{"pear", "fig", "banana", "Apple"}) {
+		t.Errorf("unexpected Descend result %v", got)
+	}
+}
@@ -17,15 +17,23 @@ limitations under the License.
 package containers
 
 import (
+	"context"
+	"errors"
 	"sync"
 )
 
+// ErrStackClosed is returned by the blocking Stack operations once the stack
+// has been closed.
+var ErrStackClosed = errors.New("containers: stack is closed")
+
 // Stack data structure implementation that is thread safe.
 type Stack[T any] struct {
 	mu       sync.RWMutex
 	capacity int
 	values   []T
 	size     int
+	closed   bool
+	changed  chan struct{}
 }
 
 // NewStack creates an empty stack with a predefined capacity.
@@ -46,25 +54,79 @@ func (s *Stack[T]) Pop() (T, bool) {
 		var empty T
 		return empty, false
 	}
-	last := s.values[len(s.values)-1]
-	s.values = s.values[:len(s.values)-1]
-	s.size--
-	return last, true
+	return s.pop(), true
 }
 
 // Push an item to the top of the stack. If the stack has reached it's capacity,
-// it will return ErrStackFull.
+// or has been closed, it will return false.
 func (s *Stack[T]) Push(item T) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.IsFull() {
+	if s.closed || s.IsFull() {
 		return false
 	}
-	s.size++
-	s.values = append(s.values, item)
+	s.push(item)
 	return true
 }
 
+// PushWait pushes an item to the top of the stack, blocking until there is
+// room for it. It returns ErrStackClosed if the stack is closed, or the
+// context's error if ctx is done before the item could be pushed.
+func (s *Stack[T]) PushWait(ctx context.Context, item T) error {
+	s.mu.Lock()
+	for {
+		if s.closed {
+			s.mu.Unlock()
+			return ErrStackClosed
+		}
+		if !s.IsFull() {
+			s.push(item)
+			s.mu.Unlock()
+			return nil
+		}
+		if err := s.wait(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// PopWait returns the top value in the stack, blocking until a value is
+// available. Values pushed before the stack was closed can still be popped;
+// once a closed stack is empty, it returns ErrStackClosed. If ctx is done
+// before a value is available, it returns the context's error.
+func (s *Stack[T]) PopWait(ctx context.Context) (T, error) {
+	s.mu.Lock()
+	for {
+		if !s.IsEmpty() {
+			v := s.pop()
+			s.mu.Unlock()
+			return v, nil
+		}
+		if s.closed {
+			s.mu.Unlock()
+			var empty T
+			return empty, ErrStackClosed
+		}
+		if err := s.wait(ctx); err != nil {
+			var empty T
+			return empty, err
+		}
+	}
+}
+
+// Close closes the stack for further pushes and wakes every goroutine blocked
+// in PushWait or PopWait. Values remaining in the stack can still be popped.
+// Calling Close more than once has no effect.
+func (s *Stack[T]) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.broadcast()
+}
+
 // Peek returns the value at the top of the stack. If the stack is empty,
 // it will return the "default" value of type T, and false.
 func (s *Stack[T]) Peek() (T, bool) {
@@ -91,3 +153,48 @@ func (s *Stack[T]) IsFull() bool {
 func (s *Stack[T]) Size() int {
 	return s.size
 }
+
+// push adds item to the top of the stack and wakes any waiters. The caller
+// must hold the lock.
+func (s *Stack[T]) push(item T) {
+	s.size++
+	s.values = append(s.values, item)
+	s.broadcast()
+}
+
+// pop removes the top value of a non-empty stack and wakes any waiters. The
+// caller must hold the lock.
+func (s *Stack[T]) pop() T {
+	last := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	s.size--
+	s.broadcast()
+	return last
+}
+
+// wait releases the lock and parks until the stack changes or ctx is done.
+// The lock is reacquired when wait returns nil, and left released when it
+// returns the context's error. The caller must hold the lock.
+func (s *Stack[T]) wait(ctx context.Context) error {
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	changed := s.changed
+	s.mu.Unlock()
+	select {
+	case <-changed:
+		s.mu.Lock()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// broadcast wakes every goroutine parked in wait. The caller must hold the
+// lock.
+func (s *Stack[T]) broadcast() {
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
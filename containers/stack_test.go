@@ -17,7 +17,11 @@ limitations under the License.
 package containers
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewStack(t *testing.T) {
@@ -54,3 +58,79 @@ func TestNewStack(t *testing.T) {
 		t.Errorf("expected false got %v instead", ok)
 	}
 }
+
+func TestStack_PushWaitPopWait(t *testing.T) {
+	s := NewStack[int](2)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if err := s.PushWait(ctx, i); err != nil {
+				t.Errorf("unexpected error from PushWait: %v", err)
+			}
+		}
+		s.Close()
+	}()
+
+	seen := 0
+	for {
+		_, err := s.PopWait(ctx)
+		if errors.Is(err, ErrStackClosed) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error from PopWait: %v", err)
+		}
+		seen++
+	}
+	wg.Wait()
+	if seen != 10 {
+		t.Errorf("expected to pop 10 values, got %v instead", seen)
+	}
+	if s.Push(1) {
+		t.Error("expected Push on closed stack to return false")
+	}
+	if err := s.PushWait(ctx, 1); !errors.Is(err, ErrStackClosed) {
+		t.Errorf("expected ErrStackClosed, got %v instead", err)
+	}
+}
+
+func TestStack_WaitContextCancelled(t *testing.T) {
+	s := NewStack[int](1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.PopWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v instead", err)
+	}
+
+	s.Push(1)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.PushWait(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v instead", err)
+	}
+	if v, _ := s.Pop(); v != 1 {
+		t.Errorf("expected 1, got %v instead", v)
+	}
+}
+
+func TestStack_CloseWakesWaiters(t *testing.T) {
+	s := NewStack[int](1)
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := s.PopWait(context.Background())
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	for i := 0; i < 3; i++ {
+		if err := <-errs; !errors.Is(err, ErrStackClosed) {
+			t.Errorf("expected ErrStackClosed, got %v instead", err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+	"sync"
+)
+
+// FallibleFn is a generic function that returns a value of type T, or an
+// error if no value could be generated.
+type FallibleFn[T any] func() (T, error)
+
+// FallibleGenerator is a Generator whose function can fail. The first error
+// returned by the function stops generation, and is reported by Err once the
+// channel returned by Iter or IterContext has been closed.
+type FallibleGenerator[T any] struct {
+	fn   FallibleFn[T]
+	size int
+	mu   sync.Mutex
+	err  error
+}
+
+// NewFallible is the factory method to create a new FallibleGenerator. The
+// number of values that can be generated is controlled by the size parameter.
+// fn is the function that returns a given value of type T, or an error.
+func NewFallible[T any](size int, fn FallibleFn[T]) *FallibleGenerator[T] {
+	return &FallibleGenerator[T]{
+		fn:   fn,
+		size: size,
+	}
+}
+
+// Iter returns a read-only channel that will have values sent through it
+// from a separate go routine, until size values have been generated or fn
+// returns an error. The channel must be drained, otherwise the go routine is
+// leaked; use IterContext to be able to stop early.
+func (g *FallibleGenerator[T]) Iter() <-chan T {
+	return g.IterContext(context.Background())
+}
+
+// IterContext returns a read-only channel that will have values sent through
+// it from a separate go routine, until size values have been generated, fn
+// returns an error or ctx is done. If generation stopped because ctx is done,
+// Err reports the context's error.
+func (g *FallibleGenerator[T]) IterContext(ctx context.Context) <-chan T {
+	c := make(chan T)
+	g.setErr(nil)
+
+	go func() {
+		defer close(c)
+		for i := 0; i < g.size; i++ {
+			if err := ctx.Err(); err != nil {
+				g.setErr(err)
+				return
+			}
+			v, err := g.fn()
+			if err != nil {
+				g.setErr(err)
+				return
+			}
+			select {
+			case c <- v:
+			case <-ctx.Done():
+				g.setErr(ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// Err returns the error that stopped the most recent iteration, or nil if it
+// generated every value. It should be called after the channel returned by
+// Iter or IterContext has been closed.
+func (g *FallibleGenerator[T]) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// setErr records err as the result of the current iteration.
+func (g *FallibleGenerator[T]) setErr(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+}
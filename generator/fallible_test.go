@@ -0,0 +1,72 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFallibleGenerator(t *testing.T) {
+	errBoom := errors.New("boom")
+	i := 0
+	g := NewFallible(10, func() (int, error) {
+		i++
+		if i == 4 {
+			return 0, errBoom
+		}
+		return i, nil
+	})
+
+	var got []int
+	for v := range g.Iter() {
+		got = append(got, v)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 values before the error, got %v instead", got)
+	}
+	if !errors.Is(g.Err(), errBoom) {
+		t.Errorf("expected Err to return %v, got %v instead", errBoom, g.Err())
+	}
+
+	ok := NewFallible(5, func() (string, error) {
+		return "ok", nil
+	})
+	count := 0
+	for range ok.Iter() {
+		count++
+	}
+	if count != 5 || ok.Err() != nil {
+		t.Errorf("expected 5 values and no error, got %v values and %v", count, ok.Err())
+	}
+}
+
+func TestFallibleGenerator_IterContext(t *testing.T) {
+	g := NewFallible(100, func() (int, error) {
+		return 1, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	c := g.IterContext(ctx)
+	<-c
+	cancel()
+	for range c {
+	}
+	if !errors.Is(g.Err(), context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v instead", g.Err())
+	}
+}
@@ -16,6 +16,10 @@ limitations under the License.
 
 package generator
 
+import (
+	"context"
+)
+
 // GeneratorFn is a generic function to returns a value of type T.
 type GeneratorFn[T any] func() T
 
@@ -37,14 +41,29 @@ func New[T any](size int, fn GeneratorFn[T]) *Generator[T] {
 }
 
 // Iter returns a read-only channel that will have values sent through it
-// from a separate go routine.
+// from a separate go routine. The channel must be drained, otherwise the go
+// routine is leaked; use IterContext to be able to stop early.
 func (g *Generator[T]) Iter() <-chan T {
+	return g.IterContext(context.Background())
+}
+
+// IterContext returns a read-only channel that will have values sent through
+// it from a separate go routine. Generation stops and the channel is closed
+// once ctx is done, so consumers can stop reading early by cancelling ctx.
+func (g *Generator[T]) IterContext(ctx context.Context) <-chan T {
 	c := make(chan T)
 
 	go func() {
 		defer close(c)
 		for i := 0; i < g.size; i++ {
-			c <- g.fn()
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case c <- g.fn():
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
@@ -17,6 +17,7 @@ limitations under the License.
 package generator
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -71,3 +72,29 @@ func TestNewGenerator(t *testing.T) {
 		t.Errorf("expected 20 users to be generated, got %v instead", uCount)
 	}
 }
+
+func TestGenerator_IterContext(t *testing.T) {
+	g := New(1000, func() int {
+		return 1
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	count := 0
+	for range g.IterContext(ctx) {
+		count++
+		if count == 5 {
+			cancel()
+			break
+		}
+	}
+
+	// The channel must be closed once the context is cancelled, even though
+	// the consumer stopped reading before the generator was exhausted.
+	for range g.IterContext(ctx) {
+		t.Error("expected no values from a generator with a cancelled context")
+	}
+	if count != 5 {
+		t.Errorf("expected 5 values before cancelling, got %v instead", count)
+	}
+}
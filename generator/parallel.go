@@ -0,0 +1,122 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// parallelJob is a value dispatched to a ParallelMap worker, along with the
+// channel its result is delivered on.
+type parallelJob[T any, U any] struct {
+	value  T
+	result chan U
+}
+
+// ParallelMap yields fn applied to every value of in, like Map, but runs fn
+// on the given number of worker go routines. Values are yielded in the same
+// order as they were received from in; a slow value holds back the values
+// behind it, with at most 2*workers values in flight at any time. If workers
+// is not positive, runtime.GOMAXPROCS(0) workers are used.
+//
+// Like the other stages, the returned channel is closed once in is closed or
+// ctx is done, and cancelling ctx releases every worker once it has finished
+// its current call to fn.
+func ParallelMap[T any, U any](ctx context.Context, in <-chan T, workers int, fn func(T) U) <-chan U {
+	workers = workerCount(workers)
+	jobs := make(chan parallelJob[T, U])
+	pending := make(chan chan U, 2*workers)
+
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			job := parallelJob[T, U]{value: v, result: make(chan U, 1)}
+			if !send(ctx, pending, job.result) || !send(ctx, jobs, job) {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				job.result <- fn(job.value)
+			}
+		}()
+	}
+
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for result := range pending {
+			u, ok := recv(ctx, result)
+			if !ok || !send(ctx, out, u) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ParallelMapUnordered yields fn applied to every value of in, running fn on
+// the given number of worker go routines. Values are yielded as soon as they
+// are ready, so their order is not preserved, and at most workers values are
+// in flight at any time. If workers is not positive, runtime.GOMAXPROCS(0)
+// workers are used.
+//
+// Like the other stages, the returned channel is closed once in is closed or
+// ctx is done, and cancelling ctx releases every worker once it has finished
+// its current call to fn.
+func ParallelMapUnordered[T any, U any](ctx context.Context, in <-chan T, workers int, fn func(T) U) <-chan U {
+	workers = workerCount(workers)
+	out := make(chan U)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok := recv(ctx, in)
+				if !ok || !send(ctx, out, fn(v)) {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// workerCount returns n, or the number of usable CPUs if n is not positive.
+func workerCount(n int) int {
+	if n <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
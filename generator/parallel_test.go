@@ -0,0 +1,101 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jitter sleeps for a short random duration and returns v squared.
+func jitter(v int) int {
+	time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+	return v * v
+}
+
+func TestParallelMap_PreservesOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := collect(ParallelMap(ctx, counter(200).IterContext(ctx), 8, jitter))
+	if len(got) != 200 {
+		t.Fatalf("expected 200 values, got %v instead", len(got))
+	}
+	for i, v := range got {
+		if v != (i+1)*(i+1) {
+			t.Fatalf("expected value %v at index %v, got %v instead", (i+1)*(i+1), i, v)
+		}
+	}
+}
+
+func TestParallelMapUnordered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var inFlight, maxInFlight int64
+	got := collect(ParallelMapUnordered(ctx, counter(200).IterContext(ctx), 4, func(v int) int {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			m := atomic.LoadInt64(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+				break
+			}
+		}
+		defer atomic.AddInt64(&inFlight, -1)
+		return jitter(v)
+	}))
+	sort.Ints(got)
+	want := make([]int, 200)
+	for i := range want {
+		want[i] = (i + 1) * (i + 1)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Error("expected every value to be mapped exactly once")
+	}
+	if maxInFlight > 4 {
+		t.Errorf("expected at most 4 values in flight, got %v", maxInFlight)
+	}
+}
+
+func TestParallelMap_CancelReleasesWorkers(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, stage := range []func(context.Context, <-chan int, int, func(int) int) <-chan int{
+		ParallelMap[int, int],
+		ParallelMapUnordered[int, int],
+	} {
+		c := stage(ctx, counter(1_000_000).IterContext(ctx), 4, jitter)
+		<-c
+		<-c
+	}
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("expected %v goroutines after cancelling, got %v", before, n)
+	}
+}
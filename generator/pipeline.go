@@ -0,0 +1,236 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+)
+
+// The stage functions in this file each consume a channel, such as the one
+// returned by Generator.IterContext, and return a new channel that yields the
+// transformed values in order from a separate go routine. The returned
+// channel is closed once the input channel is closed, the stage has nothing
+// more to yield, or ctx is done.
+//
+// A stage that stops early, such as Take, closes its channel and then keeps
+// draining its input until it is closed, so the go routines feeding it finish
+// even if ctx is never cancelled. Inputs that never end, such as a generator
+// of unbounded size, are only released when ctx is done.
+
+// Pair holds the values yielded together by Zip.
+type Pair[A any, B any] struct {
+	First  A
+	Second B
+}
+
+// Map yields fn applied to every value of in.
+func Map[T any, U any](ctx context.Context, in <-chan T, fn func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			if !send(ctx, out, fn(v)) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Filter yields the values of in for which pred returns true.
+func Filter[T any](ctx context.Context, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			if pred(v) && !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Take yields the first n values of in.
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer drain(ctx, in)
+		defer close(out)
+		for i := 0; i < n; i++ {
+			v, ok := recv(ctx, in)
+			if !ok || !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Skip discards the first n values of in, and yields the rest.
+func Skip[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		i := 0
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			if i < n {
+				i++
+				continue
+			}
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// TakeWhile yields the values of in until pred returns false for one of
+// them.
+func TakeWhile[T any](ctx context.Context, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer drain(ctx, in)
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok || !pred(v) || !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Chunk groups the values of in into slices of size values. The last slice
+// holds the remaining values and may be shorter. Chunk panics if size is not
+// positive.
+func Chunk[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
+	if size <= 0 {
+		panic("generator: chunk size must be positive")
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		batch := make([]T, 0, size)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				break
+			}
+			batch = append(batch, v)
+			if len(batch) < size {
+				continue
+			}
+			if !send(ctx, out, batch) {
+				return
+			}
+			batch = make([]T, 0, size)
+		}
+		if len(batch) > 0 && ctx.Err() == nil {
+			send(ctx, out, batch)
+		}
+	}()
+	return out
+}
+
+// Zip pairs up the values of a and b in order, until either of them is
+// closed.
+func Zip[A any, B any](ctx context.Context, a <-chan A, b <-chan B) <-chan Pair[A, B] {
+	out := make(chan Pair[A, B])
+	go func() {
+		defer drain(ctx, b)
+		defer drain(ctx, a)
+		defer close(out)
+		for {
+			first, ok := recv(ctx, a)
+			if !ok {
+				return
+			}
+			second, ok := recv(ctx, b)
+			if !ok || !send(ctx, out, Pair[A, B]{First: first, Second: second}) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Flatten yields every value of every slice received from in, in order.
+func Flatten[T any](ctx context.Context, in <-chan []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			vs, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			for _, v := range vs {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// send sends v on out, and reports false if ctx was done first.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// recv receives a value from in, and reports false if in is closed or ctx
+// was done first.
+func recv[T any](ctx context.Context, in <-chan T) (T, bool) {
+	select {
+	case v, ok := <-in:
+		return v, ok
+	case <-ctx.Done():
+		var empty T
+		return empty, false
+	}
+}
+
+// drain discards the values of in until it is closed or ctx is done, so that
+// the go routine sending on in can finish.
+func drain[T any](ctx context.Context, in <-chan T) {
+	for {
+		if _, ok := recv(ctx, in); !ok {
+			return
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// counter returns a generator that yields 1, 2, 3, ... up to size.
+func counter(size int) *Generator[int] {
+	i := 0
+	return New(size, func() int {
+		i++
+		return i
+	})
+}
+
+// collect drains c into a slice.
+func collect[T any](c <-chan T) []T {
+	var out []T
+	for v := range c {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestPipeline_Stages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type test struct {
+		name string
+		got  func() any
+		want any
+	}
+
+	tests := []test{
+		{
+			name: "map",
+			got: func() any {
+				return collect(Map(ctx, counter(3).IterContext(ctx), strconv.Itoa))
+			},
+			want: []string{"1", "2", "3"},
+		},
+		{
+			name: "filter",
+			got: func() any {
+				return collect(Filter(ctx, counter(6).IterContext(ctx), func(v int) bool {
+					return v%2 == 0
+				}))
+			},
+			want: []int{2, 4, 6},
+		},
+		{
+			name: "take",
+			got: func() any {
+				return collect(Take(ctx, counter(100).IterContext(ctx), 3))
+			},
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "skip",
+			got: func() any {
+				return collect(Skip(ctx, counter(5).IterContext(ctx), 3))
+			},
+			want: []int{4, 5},
+		},
+		{
+			name: "take while",
+			got: func() any {
+				return collect(TakeWhile(ctx, counter(100).IterContext(ctx), func(v int) bool {
+					return v < 4
+				}))
+			},
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "chunk",
+			got: func() any {
+				return collect(Chunk(ctx, counter(5).IterContext(ctx), 2))
+			},
+			want: [][]int{{1, 2}, {3, 4}, {5}},
+		},
+		{
+			name: "zip",
+			got: func() any {
+				return collect(Zip(ctx, counter(3).IterContext(ctx), Map(ctx, counter(10).IterContext(ctx), strconv.Itoa)))
+			},
+			want: []Pair[int, string]{{1, "1"}, {2, "2"}, {3, "3"}},
+		},
+		{
+			name: "flatten",
+			got: func() any {
+				return collect(Flatten(ctx, Chunk(ctx, counter(5).IterContext(ctx), 2)))
+			},
+			want: []int{1, 2, 3, 4, 5},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.got()
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("%v: expected %v, got %v instead", tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPipeline_CancelReleasesGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	evens := Filter(ctx, counter(1_000_000).IterContext(ctx), func(v int) bool {
+		return v%2 == 0
+	})
+	got := collect(Take(ctx, Map(ctx, evens, func(v int) int {
+		return v * 10
+	}), 2))
+	if !reflect.DeepEqual(got, []int{20, 40}) {
+		t.Errorf("expected [20 40], got %v instead", got)
+	}
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("expected %v goroutines after cancelling, got %v", before, n)
+	}
+}
+
+func TestPipeline_EarlyStopReleasesGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx := context.Background()
+
+	type test struct {
+		name string
+		run  func() any
+		want any
+	}
+
+	tests := []test{
+		{
+			name: "take",
+			run: func() any {
+				return collect(Take(ctx, Map(ctx, counter(1000).IterContext(ctx), strconv.Itoa), 2))
+			},
+			want: []string{"1", "2"},
+		},
+		{
+			name: "take while",
+			run: func() any {
+				return collect(TakeWhile(ctx, counter(1000).IterContext(ctx), func(v int) bool {
+					return v < 3
+				}))
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "zip",
+			run: func() any {
+				return collect(Zip(ctx, counter(2).IterContext(ctx), counter(1000).IterContext(ctx)))
+			},
+			want: []Pair[int, int]{{1, 1}, {2, 2}},
+		},
+	}
+
+	for _, tc := range tests {
+		if got := tc.run(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: expected %v, got %v instead", tc.name, tc.want, got)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("expected %v goroutines after stopping early, got %v", before, n)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/bradleybonitatibus/rig
 
-go 1.19
+go 1.21
+
+require github.com/lib/pq v1.10.9
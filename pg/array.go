@@ -0,0 +1,179 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Array is a Postgres array of T, which implements sql.Scanner and
+// driver.Valuer with the array text format, such as {1,2,NULL}, so that it
+// works with any driver. Elements are converted like the values of
+// database/sql, and NULL elements are scanned into pointer or sql.Null*
+// types of T, such as Array[*int64] or Array[sql.NullString]. An
+// Array[Array[T]] is a multidimensional array.
+// See more at https://www.postgresql.org/docs/13/arrays.html#ARRAYS-IO.
+type Array[T any] []T
+
+// arrayLiteral is implemented by Array, whose values are written unquoted
+// when nested in another Array.
+type arrayLiteral interface {
+	pgArray()
+}
+
+func (Array[T]) pgArray() {}
+
+// Scan implements sql.Scanner. A NULL array is scanned into a nil Array.
+func (a *Array[T]) Scan(src any) error {
+	text, ok, err := srcText(src)
+	if err != nil || !ok {
+		*a = nil
+		return err
+	}
+	elems, err := parseArray(text)
+	if err != nil {
+		return err
+	}
+	out := make(Array[T], len(elems))
+	for i, e := range elems {
+		if err := parseText(reflect.ValueOf(&out[i]).Elem(), e); err != nil {
+			return fmt.Errorf("pg: scan array element %d: %w", i+1, err)
+		}
+	}
+	*a = out
+	return nil
+}
+
+// Value implements driver.Valuer. A nil Array is NULL.
+func (a Array[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		s, ok, err := formatText(v)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("pg: array element %d: %w", i+1, err)
+		case !ok:
+			b.WriteString("NULL")
+		case isArrayLiteral(v):
+			b.WriteString(s)
+		default:
+			b.WriteString(quoteText(s))
+		}
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// isArrayLiteral reports whether v is a nested Array.
+func isArrayLiteral(v any) bool {
+	_, ok := v.(arrayLiteral)
+	return ok
+}
+
+// parseArray splits the array literal text into its elements, where NULL is
+// nil. Nested arrays are returned as their literal text.
+func parseArray(text string) ([]*string, error) {
+	s := strings.TrimSpace(text)
+	// Arrays with lower bounds other than 1 are prefixed with their
+	// dimensions, such as [0:1]={1,2}.
+	if strings.HasPrefix(s, "[") {
+		if i := strings.Index(s, "="); i >= 0 {
+			s = s[i+1:]
+		}
+	}
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("pg: invalid array %q", text)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	var elems []*string
+	for s != "" {
+		var (
+			elem *string
+			err  error
+		)
+		switch s[0] {
+		case '{':
+			end, err := matchingBrace(s)
+			if err != nil {
+				return nil, fmt.Errorf("pg: invalid array %q: %w", text, err)
+			}
+			nested := s[:end+1]
+			elem, s = &nested, s[end+1:]
+		case '"':
+			var quoted string
+			quoted, s, err = readQuoted(s, false)
+			if err != nil {
+				return nil, fmt.Errorf("pg: invalid array %q: %w", text, err)
+			}
+			elem = &quoted
+		default:
+			end := strings.IndexAny(s, ",}")
+			if end < 0 {
+				end = len(s)
+			}
+			unquoted := strings.TrimRightFunc(s[:end], unicode.IsSpace)
+			if !strings.EqualFold(unquoted, "NULL") {
+				elem = &unquoted
+			}
+			s = s[end:]
+		}
+		elems = append(elems, elem)
+
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+		if s[0] != ',' {
+			return nil, fmt.Errorf("pg: invalid array %q: expected a comma", text)
+		}
+		s = strings.TrimLeftFunc(s[1:], unicode.IsSpace)
+	}
+	return elems, nil
+}
+
+// matchingBrace returns the index of the brace closing the nested array at
+// the start of s.
+func matchingBrace(s string) (int, error) {
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated nested array %q", s)
+}
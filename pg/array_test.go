@@ -0,0 +1,112 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestArray_Scan(t *testing.T) {
+	one, three := int64(1), int64(3)
+	var ints Array[*int64]
+	if err := ints.Scan("{1, NULL ,3}"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Array[*int64]{&one, nil, &three}); !reflect.DeepEqual(ints, want) {
+		t.Errorf("expected %v, got %v instead", want, ints)
+	}
+
+	var strs Array[string]
+	if err := strs.Scan([]byte(`{a,"b c","d\"e","NULL","f\\g",""}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Array[string]{"a", "b c", `d"e`, "NULL", `f\g`, ""}); !reflect.DeepEqual(strs, want) {
+		t.Errorf("expected %q, got %q instead", want, strs)
+	}
+
+	var nested Array[Array[int]]
+	if err := nested.Scan(`[0:1][1:2]={{1,2},{3,4}}`); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Array[Array[int]]{{1, 2}, {3, 4}}); !reflect.DeepEqual(nested, want) {
+		t.Errorf("expected %v, got %v instead", want, nested)
+	}
+
+	var nullable Array[sql.NullString]
+	if err := nullable.Scan(`{x,NULL}`); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Array[sql.NullString]{{String: "x", Valid: true}, {}}); !reflect.DeepEqual(nullable, want) {
+		t.Errorf("expected %v, got %v instead", want, nullable)
+	}
+
+	if err := ints.Scan(nil); err != nil || ints != nil {
+		t.Errorf("expected NULL to scan into a nil array, got %v, %v instead", ints, err)
+	}
+	var empty Array[int]
+	if err := empty.Scan("{}"); err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("expected an empty array, got %v, %v instead", empty, err)
+	}
+
+	for _, invalid := range []string{"1,2", "{1,2", `{"a}`, "{{1,2}", "{a b c,}x", "{NULL}"} {
+		var a Array[int]
+		if err := a.Scan(invalid); err == nil {
+			t.Errorf("expected %q to be rejected, got %v instead", invalid, a)
+		}
+	}
+}
+
+func TestArray_Value(t *testing.T) {
+	two := 2
+	type test struct {
+		name  string
+		value driver.Valuer
+		want  driver.Value
+	}
+	tests := []test{
+		{"ints", Array[*int]{nil, &two}, `{NULL,"2"}`},
+		{"strings", Array[string]{`a"b`, `c\d`, "NULL"}, `{"a\"b","c\\d","NULL"}`},
+		{"nested", Array[Array[int]]{{1, 2}, {3, 4}}, `{{"1","2"},{"3","4"}}`},
+		{"bytea", Array[[]byte]{{0xff}, nil, {}}, `{"\\xff",NULL,"\\x"}`},
+		{"nil", Array[int](nil), nil},
+	}
+	for _, tc := range tests {
+		v, err := tc.value.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.want {
+			t.Errorf("%s: expected %v, got %v instead", tc.name, tc.want, v)
+		}
+	}
+}
+
+func TestArray_RoundTrip(t *testing.T) {
+	in := Array[Array[*string]]{{ptr("a,b"), nil}, {ptr(`{"}`), ptr("")}}
+	var out Array[Array[*string]]
+	roundTrip(t, in, &out)
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %v, got %v instead", in, out)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MaxBindParameters is the largest number of bind parameters a single
+// Postgres statement can have.
+const MaxBindParameters = 65535
+
+// Execer executes statements. It is implemented by *sql.DB, *sql.Conn,
+// *sql.Tx, *DB, *Tx and *Router.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// OnConflict is the ON CONFLICT clause of a BulkInsert.
+type OnConflict struct {
+	// Columns is the conflict target, such as the columns of a unique index.
+	Columns []string
+	// Constraint names the conflict target constraint instead of Columns.
+	Constraint string
+	// Update lists the columns set to their inserted value when a row
+	// conflicts. When it is empty, conflicting rows are skipped with DO
+	// NOTHING.
+	Update []string
+}
+
+// InsertOptions configures a BulkInsert.
+type InsertOptions struct {
+	// Columns limits the inserted columns, which defaults to every mapped
+	// field of the struct.
+	Columns []string
+	// BatchSize is the maximum number of rows per INSERT statement. The
+	// batches are always kept under MaxBindParameters, which is also the
+	// default.
+	BatchSize int
+	// OnConflict adds an ON CONFLICT clause to every INSERT statement.
+	OnConflict *OnConflict
+}
+
+// BulkInsert inserts rows into table with multi-row INSERT statements, and
+// returns the number of rows written as reported by the driver. Rows skipped
+// by OnConflict are therefore not counted. Columns are mapped to the fields
+// of T, a struct or a pointer to one, like ScanAll does, and the values of
+// nil embedded struct pointers are NULL. opts may be nil to use the defaults.
+//
+// ScanAll matches columns case insensitively, so column names, including db
+// tags such as `db:"CreatedAt"` and the columns of opts, are written in lower
+// case, like unquoted identifiers are folded by Postgres. Columns whose name
+// has upper case letters cannot be written.
+//
+// The rows are split into as many statements as needed to stay under
+// MaxBindParameters. Statements are executed one after another, so db
+// should be a transaction, such as the one of WithTx, for the insert to be
+// atomic.
+func BulkInsert[T any](ctx context.Context, db Execer, table string, rows []T, opts *InsertOptions) (int64, error) {
+	o := InsertOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	columns, err := rowColumns[T](o.Columns)
+	if err != nil {
+		return 0, err
+	}
+	batch := MaxBindParameters / len(columns)
+	if o.BatchSize > 0 && o.BatchSize < batch {
+		batch = o.BatchSize
+	}
+	conflict, err := onConflictClause(o.OnConflict)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	for start := 0; start < len(rows); start += batch {
+		end := start + batch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		args := make([]any, 0, (end-start)*len(columns))
+		for _, row := range rows[start:end] {
+			if args, err = rowValues(reflect.ValueOf(row), columns, args); err != nil {
+				return written, err
+			}
+		}
+		query := insertStatement(table, columns, end-start, conflict)
+		res, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return written, fmt.Errorf("pg: bulk insert rows %d to %d: %w", start, end-1, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return written, fmt.Errorf("pg: bulk insert rows %d to %d: %w", start, end-1, err)
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// insertStatement builds an INSERT of n rows of columns into table.
+func insertStatement(table string, columns []structColumn, n int, conflict string) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(quoteIdentifier(table))
+	b.WriteString(" (")
+	b.WriteString(columnList(columns))
+	b.WriteString(") VALUES ")
+	param := 1
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := range columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(param))
+			param++
+		}
+		b.WriteByte(')')
+	}
+	b.WriteString(conflict)
+	return b.String()
+}
+
+// onConflictClause renders c, or an empty string if c is nil.
+func onConflictClause(c *OnConflict) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	b.WriteString(" ON CONFLICT")
+	switch {
+	case c.Constraint != "" && len(c.Columns) > 0:
+		return "", fmt.Errorf("pg: on conflict: both columns and a constraint given")
+	case c.Constraint != "":
+		b.WriteString(" ON CONSTRAINT ")
+		b.WriteString(quoteIdentifier(c.Constraint))
+	case len(c.Columns) > 0:
+		b.WriteString(" (")
+		b.WriteString(identifierList(c.Columns))
+		b.WriteByte(')')
+	case len(c.Update) > 0:
+		return "", fmt.Errorf("pg: on conflict: DO UPDATE requires columns or a constraint")
+	}
+	if len(c.Update) == 0 {
+		b.WriteString(" DO NOTHING")
+		return b.String(), nil
+	}
+	b.WriteString(" DO UPDATE SET ")
+	for i, col := range c.Update {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		q := quoteIdentifier(strings.ToLower(col))
+		b.WriteString(q)
+		b.WriteString(" = EXCLUDED.")
+		b.WriteString(q)
+	}
+	return b.String(), nil
+}
+
+// rowColumns returns the columns of the struct type T to write, which are
+// every mapped field, or the named ones.
+func rowColumns[T any](names []string) ([]structColumn, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if !isStruct(t) {
+		return nil, fmt.Errorf("pg: cannot write rows of %s, expected a struct", t)
+	}
+	m := structMappingOf(t)
+	if len(names) == 0 {
+		if len(m.columns) == 0 {
+			return nil, fmt.Errorf("pg: %s has no mapped fields", t)
+		}
+		return m.columns, nil
+	}
+	columns := make([]structColumn, len(names))
+	for i, name := range names {
+		index, ok := m.fields[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q in %s", ErrUnmappedColumn, name, t)
+		}
+		columns[i] = structColumn{name: name, index: index}
+	}
+	return columns, nil
+}
+
+// rowValues appends the values of columns in row to args. The values of nil
+// embedded struct pointers are nil.
+func rowValues(row reflect.Value, columns []structColumn, args []any) ([]any, error) {
+	if row.Kind() == reflect.Pointer {
+		if row.IsNil() {
+			return args, fmt.Errorf("pg: cannot write a nil %s", row.Type())
+		}
+		row = row.Elem()
+	}
+	for _, c := range columns {
+		v, ok := fieldValue(row, c.index)
+		if !ok {
+			args = append(args, nil)
+			continue
+		}
+		args = append(args, v.Interface())
+	}
+	return args, nil
+}
+
+// fieldValue returns the field of v with the index path. If the path goes
+// through a nil embedded struct pointer, it will return the zero
+// reflect.Value, and false.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// columnList renders the quoted, comma separated names of columns, in lower
+// case.
+func columnList(columns []structColumn) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return identifierList(names)
+}
+
+// identifierList renders the quoted, comma separated column names, in lower
+// case.
+func identifierList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdentifier(strings.ToLower(n))
+	}
+	return strings.Join(quoted, ", ")
+}
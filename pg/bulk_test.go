@@ -0,0 +1,225 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type BulkBase struct {
+	TenantID int64
+}
+
+type bulkRow struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+	Note *string
+	Skip string `db:"-"`
+	*BulkBase
+}
+
+// execFixture records the statements and arguments executed on a fakeDriver,
+// and reports every row of them as affected.
+type execFixture struct {
+	mu      sync.Mutex
+	queries []string
+	args    [][]any
+}
+
+func newExecFixture(t *testing.T) (*execFixture, *sql.DB) {
+	t.Helper()
+	f := &execFixture{}
+	d, name := newFakeDriver(t)
+	d.exec = func(_, query string, args []driver.NamedValue) (driver.Result, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		values := make([]any, len(args))
+		for i, a := range args {
+			values[i] = a.Value
+		}
+		f.queries = append(f.queries, query)
+		f.args = append(f.args, values)
+		return driver.RowsAffected(len(args)), nil
+	}
+	db, err := sql.Open(name, "bulk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return f, db
+}
+
+func TestBulkInsert(t *testing.T) {
+	f, db := newExecFixture(t)
+	note := "hi"
+	rows := []bulkRow{
+		{ID: 1, Name: "a", Note: &note, BulkBase: &BulkBase{TenantID: 9}},
+		{ID: 2, Name: "b"},
+	}
+	n, err := BulkInsert(context.Background(), db, "app.users", rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 {
+		t.Errorf("expected 8 affected values, got %v instead", n)
+	}
+	want := `INSERT INTO "app"."users" ("id", "name", "note", "tenant_id") VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)`
+	if len(f.queries) != 1 || f.queries[0] != want {
+		t.Errorf("expected %v, got %v instead", want, f.queries)
+	}
+	wantArgs := []any{int64(1), "a", "hi", int64(9), int64(2), "b", nil, nil}
+	if !reflect.DeepEqual(f.args[0], wantArgs) {
+		t.Errorf("expected %v, got %v instead", wantArgs, f.args[0])
+	}
+}
+
+func TestBulkInsert_Batches(t *testing.T) {
+	type test struct {
+		name    string
+		rows    int
+		opts    *InsertOptions
+		batches []int
+	}
+	tests := []test{
+		{name: "bind parameter limit", rows: 40000, opts: &InsertOptions{Columns: []string{"id", "name"}}, batches: []int{32767, 7233}},
+		{name: "batch size", rows: 5, opts: &InsertOptions{Columns: []string{"id"}, BatchSize: 2}, batches: []int{2, 2, 1}},
+		{name: "no rows", rows: 0, batches: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, db := newExecFixture(t)
+			rows := make([]*bulkRow, tc.rows)
+			for i := range rows {
+				rows[i] = &bulkRow{ID: int64(i)}
+			}
+			if _, err := BulkInsert(context.Background(), db, "users", rows, tc.opts); err != nil {
+				t.Fatal(err)
+			}
+			var batches []int
+			for _, args := range f.args {
+				if len(args) > MaxBindParameters {
+					t.Errorf("expected at most %v parameters, got %v instead", MaxBindParameters, len(args))
+				}
+				batches = append(batches, len(args)/len(tc.opts.Columns))
+			}
+			if !reflect.DeepEqual(batches, tc.batches) {
+				t.Errorf("expected batches %v, got %v instead", tc.batches, batches)
+			}
+		})
+	}
+}
+
+func TestBulkInsert_OnConflict(t *testing.T) {
+	type test struct {
+		name     string
+		conflict *OnConflict
+		want     string
+		wantErr  bool
+	}
+	tests := []test{
+		{
+			name:     "do nothing",
+			conflict: &OnConflict{},
+			want:     ` ON CONFLICT DO NOTHING`,
+		},
+		{
+			name:     "columns",
+			conflict: &OnConflict{Columns: []string{"id"}, Update: []string{"name", "note"}},
+			want:     ` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "note" = EXCLUDED."note"`,
+		},
+		{
+			name:     "constraint",
+			conflict: &OnConflict{Constraint: "users_pkey"},
+			want:     ` ON CONFLICT ON CONSTRAINT "users_pkey" DO NOTHING`,
+		},
+		{
+			name:     "update without target",
+			conflict: &OnConflict{Update: []string{"name"}},
+			wantErr:  true,
+		},
+		{
+			name:     "columns and constraint",
+			conflict: &OnConflict{Columns: []string{"id"}, Constraint: "users_pkey"},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, db := newExecFixture(t)
+			opts := &InsertOptions{Columns: []string{"id"}, OnConflict: tc.conflict}
+			_, err := BulkInsert(context.Background(), db, "users", []bulkRow{{ID: 1}}, opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v instead", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			if want := `INSERT INTO "users" ("id") VALUES ($1)` + tc.want; f.queries[0] != want {
+				t.Errorf("expected %v, got %v instead", want, f.queries[0])
+			}
+		})
+	}
+}
+
+func TestBulkInsert_Errors(t *testing.T) {
+	_, db := newExecFixture(t)
+	ctx := context.Background()
+	if _, err := BulkInsert(ctx, db, "users", []int{1}, nil); err == nil {
+		t.Error("expected rows that are not structs to be rejected")
+	}
+	opts := &InsertOptions{Columns: []string{"missing"}}
+	if _, err := BulkInsert(ctx, db, "users", []bulkRow{{}}, opts); !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("expected %v, got %v instead", ErrUnmappedColumn, err)
+	}
+	if _, err := BulkInsert(ctx, db, "users", []*bulkRow{nil}, nil); err == nil {
+		t.Error("expected nil rows to be rejected")
+	}
+}
+
+func TestBulkInsert_ColumnCase(t *testing.T) {
+	type event struct {
+		ID        int64     `db:"ID"`
+		CreatedAt time.Time `db:"CreatedAt"`
+	}
+	f, db := newExecFixture(t)
+	opts := &InsertOptions{OnConflict: &OnConflict{Columns: []string{"ID"}, Update: []string{"CreatedAt"}}}
+	if _, err := BulkInsert(context.Background(), db, "events", []event{{ID: 1}}, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "events" ("id", "createdat") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "createdat" = EXCLUDED."createdat"`
+	if f.queries[0] != want {
+		t.Errorf("expected %v, got %v instead", want, f.queries[0])
+	}
+
+	// The lower case columns written are the ones ScanAll reads back.
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := queryRows(t, []string{"id", "createdat"}, []driver.Value{int64(1), created})
+	got, err := ScanAll[event](rows, &ScanOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got[0].CreatedAt.Equal(created) {
+		t.Errorf("expected %v, got %+v instead", created, got)
+	}
+}
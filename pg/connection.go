@@ -16,7 +16,19 @@ limitations under the License.
 
 package pg
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultPort is the port libpq connects to when none is configured.
+const DefaultPort = 5432
+
+// ErrInvalidConfig is returned, wrapped with the reason, when a
+// ConnectionConfig fails validation.
+var ErrInvalidConfig = errors.New("pg: invalid connection config")
 
 // SSLMode type contains the different options to provide as an option
 // to the sslmode connection string parameter.
@@ -32,36 +44,240 @@ const (
 	SSLModeVerifyFull SSLMode = "verify-full"
 )
 
+// TargetSessionAttrs type contains the different options to provide as an
+// option to the target_session_attrs connection string parameter.
+// See more at https://www.postgresql.org/docs/14/libpq-connect.html#LIBPQ-CONNECT-TARGET-SESSION-ATTRS.
+type TargetSessionAttrs string
+
+const (
+	TargetSessionAttrsAny           TargetSessionAttrs = "any" // Any is the libpq default.
+	TargetSessionAttrsReadWrite     TargetSessionAttrs = "read-write"
+	TargetSessionAttrsReadOnly      TargetSessionAttrs = "read-only"
+	TargetSessionAttrsPrimary       TargetSessionAttrs = "primary"
+	TargetSessionAttrsStandby       TargetSessionAttrs = "standby"
+	TargetSessionAttrsPreferStandby TargetSessionAttrs = "prefer-standby"
+)
+
+// LoadBalanceHosts type contains the different options to provide as an
+// option to the load_balance_hosts connection string parameter.
+// See more at https://www.postgresql.org/docs/16/libpq-connect.html#LIBPQ-CONNECT-LOAD-BALANCE-HOSTS.
+type LoadBalanceHosts string
+
+const (
+	LoadBalanceHostsDisable LoadBalanceHosts = "disable" // Disable is the libpq default.
+	LoadBalanceHostsRandom  LoadBalanceHosts = "random"
+)
+
 // ConnectionConfig is the configuration to connect to a Postgres Database.
 // Additional to the ODBC information, there are connection pool configuration
-// fields that can be set in this object.
+// fields that can be set in this object, which are applied by Open.
 type ConnectionConfig struct {
-	Host            string  `json:"host" yaml:"host"`
-	User            string  `json:"user" yaml:"user"`
-	Password        string  `json:"password" yaml:"password"`
-	Database        string  `json:"database" yaml:"database"`
-	Port            int     `json:"port" yaml:"port"`
-	SSLMode         SSLMode `json:"sslmode" yaml:"sslmode"`
-	MaxOpenConns    int     `json:"max_open_conns" yaml:"max_open_conns"`
-	ConnMaxLifetime int     `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
-	MaxIdleConns    int     `json:"max_idle_conns" yaml:"max_idle_conns"`
-	ConnMaxIdleTime int     `json:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+	Host     string `json:"host" yaml:"host"`
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Database string `json:"database" yaml:"database"`
+	Port     int    `json:"port" yaml:"port"`
+	// Hosts lists every host to try in order when connecting to a cluster
+	// with several servers. When set, it takes precedence over Host and Port.
+	Hosts   []HostPort `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	SSLMode SSLMode    `json:"sslmode" yaml:"sslmode"`
+	// Service is the name of a pg_service.conf section holding additional
+	// connection parameters, which Resolve fills in.
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+	// SSLRootCert, SSLCert and SSLKey are paths to the certificate authority,
+	// client certificate and client key files used for SSL connections.
+	SSLRootCert string `json:"sslrootcert,omitempty" yaml:"sslrootcert,omitempty"`
+	SSLCert     string `json:"sslcert,omitempty" yaml:"sslcert,omitempty"`
+	SSLKey      string `json:"sslkey,omitempty" yaml:"sslkey,omitempty"`
+	// ApplicationName is reported by the server in pg_stat_activity.
+	ApplicationName string `json:"application_name,omitempty" yaml:"application_name,omitempty"`
+	// ConnectTimeout is the maximum number of seconds to wait while
+	// connecting, zero waits indefinitely.
+	ConnectTimeout int `json:"connect_timeout,omitempty" yaml:"connect_timeout,omitempty"`
+	// Options are command-line options sent to the server at connection
+	// start, such as "-c statement_timeout=5s".
+	Options string `json:"options,omitempty" yaml:"options,omitempty"`
+	// SearchPath is the schema search path of the session. It is sent as a
+	// run-time parameter, which pgx and lib/pq both support.
+	SearchPath         string             `json:"search_path,omitempty" yaml:"search_path,omitempty"`
+	TargetSessionAttrs TargetSessionAttrs `json:"target_session_attrs,omitempty" yaml:"target_session_attrs,omitempty"`
+	LoadBalanceHosts   LoadBalanceHosts   `json:"load_balance_hosts,omitempty" yaml:"load_balance_hosts,omitempty"`
+	// Keepalives controls whether libpq uses TCP keepalives, nil keeps the
+	// libpq default of enabled. Like the other keepalive fields, it is only
+	// rendered by ODBC and URL for libpq consumers such as psql, and has no
+	// effect through Open or Connector, which leave it out of the DSN given
+	// to the database/sql driver.
+	Keepalives *bool `json:"keepalives,omitempty" yaml:"keepalives,omitempty"`
+	// KeepalivesIdle, KeepalivesInterval and KeepalivesCount tune the TCP
+	// keepalives of libpq in seconds and probes, zero keeps the system
+	// default. They have no effect through Open or Connector either.
+	KeepalivesIdle     int `json:"keepalives_idle,omitempty" yaml:"keepalives_idle,omitempty"`
+	KeepalivesInterval int `json:"keepalives_interval,omitempty" yaml:"keepalives_interval,omitempty"`
+	KeepalivesCount    int `json:"keepalives_count,omitempty" yaml:"keepalives_count,omitempty"`
+	// MaxOpenConns is the maximum number of open connections, zero is
+	// unlimited.
+	MaxOpenConns int `json:"max_open_conns" yaml:"max_open_conns"`
+	// ConnMaxLifetime is the maximum number of seconds a connection may be
+	// reused for, zero is forever.
+	ConnMaxLifetime int `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	// MaxIdleConns is the maximum number of idle connections, zero keeps the
+	// database/sql default and a negative value retains none.
+	MaxIdleConns int `json:"max_idle_conns" yaml:"max_idle_conns"`
+	// ConnMaxIdleTime is the maximum number of seconds a connection may be
+	// idle for, zero is forever.
+	ConnMaxIdleTime int `json:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+}
+
+// param is a libpq connection parameter and its value.
+type param struct {
+	key   string
+	value string
 }
 
 // ODBC formats the ConnectionConfig config into an ODBC string format.
+// Empty fields are omitted, the port defaults to 5432, and values are quoted
+// and escaped following the libpq rules.
 func (c *ConnectionConfig) ODBC() string {
-	if c.Port == 0 {
-		c.Port = 5432
+	var b strings.Builder
+	for _, p := range c.params() {
+		switch {
+		case p.key == "port":
+			ports := strings.Split(p.value, ",")
+			for i := range ports {
+				if ports[i] == "" {
+					ports[i] = strconv.Itoa(DefaultPort)
+				}
+			}
+			p.value = strings.Join(ports, ",")
+		case p.key == "sslmode" && p.value == string(SSLModePrefer):
+			continue
+		case p.value == "":
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(p.key)
+		b.WriteByte('=')
+		b.WriteString(quote(p.value))
 	}
-	strTemplate := fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s", c.Host,
-		c.Port,
-		c.User,
-		c.Database,
-		c.Password,
-	)
-
-	if c.SSLMode == "" || c.SSLMode != SSLModePrefer {
-		return fmt.Sprintf("%v sslmode=%s", strTemplate, c.SSLMode)
+	return b.String()
+}
+
+// Validate checks every field of the ConnectionConfig, and returns the joined
+// errors for every invalid field, each wrapping ErrInvalidConfig.
+func (c *ConnectionConfig) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	for _, p := range c.params() {
+		if strings.ContainsRune(p.value, 0) {
+			invalid("%s contains a NUL byte", p.key)
+		}
+	}
+	for _, a := range c.Addrs() {
+		if a.Port < 0 || a.Port > 65535 {
+			invalid("port %d is out of range", a.Port)
+		}
+		if len(c.Hosts) > 1 && strings.Contains(a.Host, ",") {
+			invalid("host %q contains a comma", a.Host)
+		}
+	}
+	switch c.SSLMode {
+	case "", SSLModeDisable, SSLModeAllow, SSLModeRequire, SSLModePrefer, SSLModeVerifyCA, SSLModeVerifyFull:
+	default:
+		invalid("unknown sslmode %q", c.SSLMode)
+	}
+	if c.SSLKey != "" && c.SSLCert == "" {
+		invalid("sslkey requires sslcert")
+	}
+	switch c.TargetSessionAttrs {
+	case "", TargetSessionAttrsAny, TargetSessionAttrsReadWrite, TargetSessionAttrsReadOnly,
+		TargetSessionAttrsPrimary, TargetSessionAttrsStandby, TargetSessionAttrsPreferStandby:
+	default:
+		invalid("unknown target_session_attrs %q", c.TargetSessionAttrs)
+	}
+	switch c.LoadBalanceHosts {
+	case "", LoadBalanceHostsDisable, LoadBalanceHostsRandom:
+	default:
+		invalid("unknown load_balance_hosts %q", c.LoadBalanceHosts)
+	}
+	for _, n := range []struct {
+		key   string
+		value int
+	}{
+		{"connect_timeout", c.ConnectTimeout},
+		{"keepalives_idle", c.KeepalivesIdle},
+		{"keepalives_interval", c.KeepalivesInterval},
+		{"keepalives_count", c.KeepalivesCount},
+	} {
+		if n.value < 0 {
+			invalid("%s must not be negative", n.key)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// params returns every libpq connection parameter of the ConnectionConfig in
+// the order they are rendered. Unset parameters have an empty value.
+func (c *ConnectionConfig) params() []param {
+	itoa := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	keepalives := ""
+	if c.Keepalives != nil {
+		keepalives = "0"
+		if *c.Keepalives {
+			keepalives = "1"
+		}
+	}
+	addrs := c.Addrs()
+	hosts := make([]string, len(addrs))
+	ports := make([]string, len(addrs))
+	for i, a := range addrs {
+		hosts[i] = a.Host
+		ports[i] = itoa(a.Port)
+	}
+	port := strings.Join(ports, ",")
+	if strings.Trim(port, ",") == "" {
+		port = ""
+	}
+	return []param{
+		{"host", strings.Join(hosts, ",")},
+		{"port", port},
+		{"user", c.User},
+		{"dbname", c.Database},
+		{"password", c.Password},
+		{"sslmode", string(c.SSLMode)},
+		{"service", c.Service},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		{"application_name", c.ApplicationName},
+		{"connect_timeout", itoa(c.ConnectTimeout)},
+		{"options", c.Options},
+		{"search_path", c.SearchPath},
+		{"target_session_attrs", string(c.TargetSessionAttrs)},
+		{"load_balance_hosts", string(c.LoadBalanceHosts)},
+		{"keepalives", keepalives},
+		{"keepalives_idle", itoa(c.KeepalivesIdle)},
+		{"keepalives_interval", itoa(c.KeepalivesInterval)},
+		{"keepalives_count", itoa(c.KeepalivesCount)},
+	}
+}
+
+// quote returns v as a libpq keyword value, surrounded by single quotes with
+// backslashes and single quotes escaped if it is empty or contains
+// whitespace, a quote or a backslash.
+func quote(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\r\f\v'\\") {
+		return v
 	}
-	return strTemplate
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(v) + "'"
 }
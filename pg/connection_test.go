@@ -17,6 +17,7 @@ limitations under the License.
 package pg
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -65,6 +66,47 @@ func TestConnectionConfig_ODBC(t *testing.T) {
 			},
 			want: "host=postgres port=5432 user=postgres dbname=postgres password=postgres",
 		},
+		{
+			name: "empty fields and sslmode are omitted",
+			cfg: &ConnectionConfig{
+				Host: pg,
+			},
+			want: "host=postgres port=5432",
+		},
+		{
+			name: "values are quoted and escaped",
+			cfg: &ConnectionConfig{
+				Host:            pg,
+				User:            pg,
+				Password:        `it's a \ secret`,
+				Database:        "my db",
+				ApplicationName: "rig",
+				Options:         "-c statement_timeout=5s",
+			},
+			want: `host=postgres port=5432 user=postgres dbname='my db' password='it\'s a \\ secret' application_name=rig options='-c statement_timeout=5s'`,
+		},
+		{
+			name: "additional libpq parameters",
+			cfg: &ConnectionConfig{
+				Host:               pg,
+				Port:               6432,
+				SSLMode:            SSLModeVerifyFull,
+				SSLRootCert:        "/etc/ssl/root.crt",
+				SSLCert:            "/etc/ssl/client.crt",
+				SSLKey:             "/etc/ssl/client.key",
+				ConnectTimeout:     10,
+				SearchPath:         "app,public",
+				TargetSessionAttrs: TargetSessionAttrsReadWrite,
+				Keepalives:         new(bool),
+				KeepalivesIdle:     30,
+				KeepalivesInterval: 5,
+				KeepalivesCount:    3,
+			},
+			want: "host=postgres port=6432 sslmode=verify-full sslrootcert=/etc/ssl/root.crt " +
+				"sslcert=/etc/ssl/client.crt sslkey=/etc/ssl/client.key connect_timeout=10 " +
+				"search_path=app,public target_session_attrs=read-write keepalives=0 " +
+				"keepalives_idle=30 keepalives_interval=5 keepalives_count=3",
+		},
 	}
 
 	t.Parallel()
@@ -78,3 +120,35 @@ func TestConnectionConfig_ODBC(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionConfig_Validate(t *testing.T) {
+	type test struct {
+		name string
+		cfg  *ConnectionConfig
+		ok   bool
+	}
+
+	tests := []test{
+		{name: "empty config", cfg: &ConnectionConfig{}, ok: true},
+		{name: "valid config", cfg: &ConnectionConfig{Host: pg, Port: 5432, SSLMode: SSLModeRequire, TargetSessionAttrs: TargetSessionAttrsStandby}, ok: true},
+		{name: "port out of range", cfg: &ConnectionConfig{Port: 70000}},
+		{name: "unknown sslmode", cfg: &ConnectionConfig{SSLMode: "sometimes"}},
+		{name: "unknown target_session_attrs", cfg: &ConnectionConfig{TargetSessionAttrs: "writable"}},
+		{name: "sslkey without sslcert", cfg: &ConnectionConfig{SSLKey: "/key"}},
+		{name: "negative connect_timeout", cfg: &ConnectionConfig{ConnectTimeout: -1}},
+		{name: "negative keepalives_count", cfg: &ConnectionConfig{KeepalivesCount: -1}},
+		{name: "NUL byte in password", cfg: &ConnectionConfig{Password: "a\x00b"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.ok && err != nil {
+				t.Errorf("%v: unexpected error %v", tc.name, err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("%v: expected ErrInvalidConfig, got %v instead", tc.name, err)
+			}
+		})
+	}
+}
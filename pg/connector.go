@@ -0,0 +1,232 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrNoSuitableHost is returned, joined with the error of every host that
+// was tried, when a Connector cannot connect to any host that satisfies the
+// TargetSessionAttrs of its ConnectionConfig.
+var ErrNoSuitableHost = errors.New("pg: no suitable host")
+
+// errSessionAttrs is the error of a host that was reached but does not
+// satisfy the TargetSessionAttrs.
+var errSessionAttrs = errors.New("server does not match target_session_attrs")
+
+// Connector is a driver.Connector that connects to the hosts of a
+// ConnectionConfig in order, or in random order when LoadBalanceHosts is
+// random, and returns the first connection whose server satisfies the
+// TargetSessionAttrs. The server is checked after connecting, so any
+// database/sql driver can be used even if it does not support multiple hosts
+// or target_session_attrs itself. Use it with sql.OpenDB.
+type Connector struct {
+	driver driver.Driver
+	cfg    ConnectionConfig
+
+	mu   sync.Mutex
+	last *HostPort
+}
+
+// NewConnector creates a Connector for cfg that opens connections with the
+// database/sql driver registered as driverName.
+func NewConnector(driverName string, cfg *ConnectionConfig) (*Connector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	// database/sql only exposes registered drivers through a DB.
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, fmt.Errorf("pg: open %s: %w", driverName, err)
+	}
+	defer db.Close()
+	return &Connector{
+		driver: db.Driver(),
+		cfg:    *cfg,
+	}, nil
+}
+
+// Connect implements driver.Connector.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	addrs := c.cfg.Addrs()
+	if c.cfg.LoadBalanceHosts == LoadBalanceHostsRandom {
+		rand.Shuffle(len(addrs), func(i, j int) {
+			addrs[i], addrs[j] = addrs[j], addrs[i]
+		})
+	}
+
+	attrs := c.cfg.TargetSessionAttrs
+	if attrs == TargetSessionAttrsPreferStandby {
+		// Like libpq, any server is accepted once no standby was found.
+		conn, err := c.connect(ctx, addrs, TargetSessionAttrsStandby)
+		if err == nil || ctx.Err() != nil {
+			return conn, err
+		}
+		attrs = TargetSessionAttrsAny
+	}
+	return c.connect(ctx, addrs, attrs)
+}
+
+// Driver implements driver.Connector.
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// LastHost returns the host of the most recent successful connection, and
+// false if no connection has been made yet.
+func (c *Connector) LastHost() (HostPort, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last == nil {
+		return HostPort{}, false
+	}
+	return *c.last, true
+}
+
+// connect tries every host in addrs in order, returning the first connection
+// that satisfies attrs.
+func (c *Connector) connect(ctx context.Context, addrs []HostPort, attrs TargetSessionAttrs) (driver.Conn, error) {
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := c.dial(ctx, addr)
+		if err == nil {
+			var ok bool
+			if ok, err = checkSession(ctx, conn, attrs); err == nil && !ok {
+				err = errSessionAttrs
+			}
+			if err != nil {
+				conn.Close()
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		chosen := addr
+		c.mu.Lock()
+		c.last = &chosen
+		c.mu.Unlock()
+		return conn, nil
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoSuitableHost, errors.Join(errs...))
+}
+
+// dial opens a connection to a single host, waiting at most ConnectTimeout
+// seconds if it is set and the driver supports contexts.
+func (c *Connector) dial(ctx context.Context, addr HostPort) (driver.Conn, error) {
+	single := c.cfg
+	single.Host, single.Port, single.Hosts = addr.Host, addr.Port, nil
+	// The hosts are chosen and checked here, so drivers that do not know
+	// these parameters must not see them.
+	single.TargetSessionAttrs, single.LoadBalanceHosts = "", ""
+	// The service is read by Resolve, and the keepalive fields are only
+	// understood by libpq. Drivers such as lib/pq send unknown keys to the
+	// server as run-time parameters, which it rejects, so as documented on
+	// ConnectionConfig.Keepalives they have no effect here.
+	single.Service = ""
+	single.Keepalives = nil
+	single.KeepalivesIdle, single.KeepalivesInterval, single.KeepalivesCount = 0, 0, 0
+	dsn := single.ODBC()
+
+	dc, ok := c.driver.(driver.DriverContext)
+	if !ok {
+		return c.driver.Open(dsn)
+	}
+	connector, err := dc.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.cfg.ConnectTimeout)*time.Second)
+		defer cancel()
+	}
+	return connector.Connect(ctx)
+}
+
+// checkSession reports whether the server conn is connected to satisfies
+// attrs.
+func checkSession(ctx context.Context, conn driver.Conn, attrs TargetSessionAttrs) (bool, error) {
+	switch attrs {
+	case TargetSessionAttrsReadWrite, TargetSessionAttrsReadOnly:
+		readOnly, err := queryBool(ctx, conn, "SHOW transaction_read_only")
+		return err == nil && readOnly == (attrs == TargetSessionAttrsReadOnly), err
+	case TargetSessionAttrsPrimary, TargetSessionAttrsStandby:
+		recovery, err := queryBool(ctx, conn, "SELECT pg_is_in_recovery()")
+		return err == nil && recovery == (attrs == TargetSessionAttrsStandby), err
+	default:
+		return true, nil
+	}
+}
+
+// queryBool runs a query returning a single boolean on conn. Booleans
+// formatted as text, such as "on" or "t", are accepted too.
+func queryBool(ctx context.Context, conn driver.Conn, query string) (bool, error) {
+	q, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return false, errors.New("driver connection does not implement driver.QueryerContext")
+	}
+	rows, err := q.QueryContext(ctx, query, nil)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, len(rows.Columns()))
+	if len(dest) == 0 {
+		return false, fmt.Errorf("%s: no columns returned", query)
+	}
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("%s: no rows returned", query)
+		}
+		return false, err
+	}
+	switch v := dest[0].(type) {
+	case bool:
+		return v, nil
+	case []byte:
+		return parseBool(string(v))
+	case string:
+		return parseBool(v)
+	default:
+		return false, fmt.Errorf("%s: unexpected value %v", query, v)
+	}
+}
+
+// parseBool parses the text forms of a Postgres boolean.
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "t", "true", "on", "yes", "1":
+		return true, nil
+	case "f", "false", "off", "no", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean %q", s)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cluster configures d to act as a cluster where every host in standbys is a
+// read-only standby, every host in down refuses connections, and every other
+// host is a primary.
+func cluster(d *fakeDriver, standbys, down []string) {
+	hostOf := func(dsn string) string {
+		for _, kv := range strings.Fields(dsn) {
+			if strings.HasPrefix(kv, "host=") {
+				return strings.TrimPrefix(kv, "host=")
+			}
+		}
+		return ""
+	}
+	contains := func(hosts []string, host string) bool {
+		for _, h := range hosts {
+			if h == host {
+				return true
+			}
+		}
+		return false
+	}
+	d.open = func(dsn string) error {
+		if contains(down, hostOf(dsn)) {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	d.query = func(dsn, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		standby := contains(standbys, hostOf(dsn))
+		value := driver.Value(standby)
+		if strings.HasPrefix(query, "SHOW") {
+			value = []byte("off")
+			if standby {
+				value = []byte("on")
+			}
+		}
+		return &fakeRows{columns: []string{"v"}, values: [][]driver.Value{{value}}}, nil
+	}
+}
+
+func TestConnector_TargetSessionAttrs(t *testing.T) {
+	hosts := []HostPort{{Host: "a"}, {Host: "b"}, {Host: "c"}}
+
+	type test struct {
+		name     string
+		attrs    TargetSessionAttrs
+		standbys []string
+		down     []string
+		want     string
+	}
+
+	tests := []test{
+		{name: "any uses first host", attrs: TargetSessionAttrsAny, standbys: []string{"a"}, want: "a"},
+		{name: "any skips down hosts", down: []string{"a"}, want: "b"},
+		{name: "read-write", attrs: TargetSessionAttrsReadWrite, standbys: []string{"a", "b"}, want: "c"},
+		{name: "read-only", attrs: TargetSessionAttrsReadOnly, standbys: []string{"b"}, want: "b"},
+		{name: "primary", attrs: TargetSessionAttrsPrimary, standbys: []string{"a"}, down: []string{"b"}, want: "c"},
+		{name: "standby", attrs: TargetSessionAttrsStandby, standbys: []string{"c"}, want: "c"},
+		{name: "prefer-standby finds standby", attrs: TargetSessionAttrsPreferStandby, standbys: []string{"b"}, want: "b"},
+		{name: "prefer-standby falls back", attrs: TargetSessionAttrsPreferStandby, down: []string{"a"}, want: "b"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d, name := newFakeDriver(t)
+			cluster(d, tc.standbys, tc.down)
+			c, err := NewConnector(name, &ConnectionConfig{Hosts: hosts, TargetSessionAttrs: tc.attrs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := c.Connect(context.Background())
+			if err != nil {
+				t.Fatalf("%v: unexpected error %v", tc.name, err)
+			}
+			conn.Close()
+			if h, _ := c.LastHost(); h.Host != tc.want {
+				t.Errorf("%v: expected host %v, got %v instead", tc.name, tc.want, h.Host)
+			}
+			for _, dsn := range d.opened() {
+				if strings.Contains(dsn, "target_session_attrs") || strings.Contains(dsn, ",") {
+					t.Errorf("%v: expected single host DSN without target_session_attrs, got %v", tc.name, dsn)
+				}
+			}
+		})
+	}
+}
+
+func TestConnector_NoSuitableHost(t *testing.T) {
+	d, name := newFakeDriver(t)
+	cluster(d, []string{"a"}, []string{"b"})
+	c, err := NewConnector(name, &ConnectionConfig{
+		Hosts:              []HostPort{{Host: "a"}, {Host: "b"}},
+		TargetSessionAttrs: TargetSessionAttrsPrimary,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Connect(context.Background())
+	if !errors.Is(err, ErrNoSuitableHost) {
+		t.Fatalf("expected ErrNoSuitableHost, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a: server does not match") || !strings.Contains(err.Error(), "b: connection refused") {
+		t.Errorf("expected error for every host, got %v", err)
+	}
+	if _, ok := c.LastHost(); ok {
+		t.Error("expected no last host after failing to connect")
+	}
+}
+
+func TestConnector_DriverDSN(t *testing.T) {
+	d, name := newFakeDriver(t)
+	keepalives := true
+	c, err := NewConnector(name, &ConnectionConfig{
+		Host:               "db",
+		User:               "app",
+		Database:           "orders",
+		Service:            "orders",
+		TargetSessionAttrs: TargetSessionAttrsAny,
+		LoadBalanceHosts:   LoadBalanceHostsDisable,
+		Keepalives:         &keepalives,
+		KeepalivesIdle:     10,
+		KeepalivesInterval: 5,
+		KeepalivesCount:    3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := c.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	want := []string{"host=db port=5432 user=app dbname=orders"}
+	if got := d.opened(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
+
+func TestConnector_LoadBalanceHosts(t *testing.T) {
+	_, name := newFakeDriver(t)
+	c, err := NewConnector(name, &ConnectionConfig{
+		Hosts:            []HostPort{{Host: "a"}, {Host: "b"}, {Host: "c"}},
+		LoadBalanceHosts: LoadBalanceHostsRandom,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		h, _ := c.LastHost()
+		seen[h.Host] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected connections to be spread over every host, got %v", seen)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// CopyFormat is the data format of a COPY stream.
+// See more at https://www.postgresql.org/docs/13/sql-copy.html#id-1.9.3.55.9.
+type CopyFormat string
+
+const (
+	CopyText CopyFormat = "text" // Text is the COPY default.
+	CopyCSV  CopyFormat = "csv"
+)
+
+// CopyOptions configures CopyFromStatement and WriteCopy.
+type CopyOptions struct {
+	// Format is the data format, defaulting to CopyText.
+	Format CopyFormat
+	// Columns limits the copied columns, which defaults to every mapped
+	// field of the struct.
+	Columns []string
+}
+
+// format returns the configured format, or the default.
+func (o *CopyOptions) format() (CopyFormat, error) {
+	if o == nil || o.Format == "" {
+		return CopyText, nil
+	}
+	switch o.Format {
+	case CopyText, CopyCSV:
+		return o.Format, nil
+	default:
+		return "", fmt.Errorf("pg: unknown copy format %q", o.Format)
+	}
+}
+
+// columns returns the configured columns, or nil.
+func (o *CopyOptions) columns() []string {
+	if o == nil {
+		return nil
+	}
+	return o.Columns
+}
+
+// CopyFromStatement returns the COPY ... FROM STDIN statement that reads the
+// stream written by WriteCopy with the same opts into table. Drivers with
+// COPY support, such as pgx's PgConn.CopyFrom, run the statement with the
+// stream as input. Like in BulkInsert, column names are written in lower
+// case.
+func CopyFromStatement[T any](table string, opts *CopyOptions) (string, error) {
+	format, err := opts.format()
+	if err != nil {
+		return "", err
+	}
+	columns, err := rowColumns[T](opts.columns())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN (FORMAT %s)", quoteIdentifier(table), columnList(columns), format), nil
+}
+
+// WriteCopy writes rows to w in the COPY text or CSV format, and returns the
+// number of rows written. Columns are mapped to the fields of T, a struct or
+// a pointer to one, like BulkInsert does, and values are rendered like the
+// elements of an Array. opts may be nil to use the defaults.
+func WriteCopy[T any](w io.Writer, rows []T, opts *CopyOptions) (int64, error) {
+	format, err := opts.format()
+	if err != nil {
+		return 0, err
+	}
+	columns, err := rowColumns[T](opts.columns())
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(w)
+	var (
+		written int64
+		values  []any
+	)
+	for i, row := range rows {
+		if values, err = rowValues(reflect.ValueOf(row), columns, values[:0]); err != nil {
+			return written, err
+		}
+		for j, v := range values {
+			if j > 0 {
+				if format == CopyCSV {
+					bw.WriteByte(',')
+				} else {
+					bw.WriteByte('\t')
+				}
+			}
+			s, ok, err := formatText(v)
+			if err != nil {
+				return written, fmt.Errorf("pg: copy row %d column %q: %w", i, columns[j].name, err)
+			}
+			if format == CopyCSV {
+				bw.WriteString(copyCSVField(s, ok))
+			} else {
+				bw.WriteString(copyTextField(s, ok))
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return written, err
+		}
+		written++
+	}
+	if err := bw.Flush(); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// copyTextReplacer escapes the characters of the COPY text format.
+var copyTextReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\b", `\b`,
+	"\f", `\f`,
+	"\v", `\v`,
+)
+
+// copyTextField renders a value in the COPY text format, where NULL is \N.
+func copyTextField(s string, ok bool) string {
+	if !ok {
+		return `\N`
+	}
+	return copyTextReplacer.Replace(s)
+}
+
+// copyCSVField renders a value in the COPY CSV format, where NULL is an
+// unquoted empty field, so that empty strings are quoted.
+func copyCSVField(s string, ok bool) string {
+	if !ok {
+		return ""
+	}
+	if s != "" && s != `\.` && !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type copyRow struct {
+	ID      int64
+	Name    string
+	Tags    Array[string]
+	Note    *string
+	Created time.Time
+	Data    []byte
+}
+
+func TestWriteCopy(t *testing.T) {
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	empty := ""
+	rows := []copyRow{
+		{ID: 1, Name: "tab\there\nnew \\ line", Tags: Array[string]{"a"}, Note: &empty, Created: created, Data: []byte{0xab}},
+		{ID: 2, Name: `say "hi", bye`, Created: created},
+		{ID: 3, Name: `\.`, Created: created, Data: []byte{}},
+	}
+	type test struct {
+		format CopyFormat
+		want   string
+	}
+	tests := []test{
+		{
+			format: CopyText,
+			want: "1\ttab\\there\\nnew \\\\ line\t{\"a\"}\t\t2022-01-02 03:04:05Z\t\\\\xab\n" +
+				"2\tsay \"hi\", bye\t\\N\t\\N\t2022-01-02 03:04:05Z\t\\N\n" +
+				"3\t\\\\.\t\\N\t\\N\t2022-01-02 03:04:05Z\t\\\\x\n",
+		},
+		{
+			format: CopyCSV,
+			want: "1,\"tab\there\nnew \\ line\",\"{\"\"a\"\"}\",\"\",2022-01-02 03:04:05Z,\\xab\n" +
+				"2,\"say \"\"hi\"\", bye\",,,2022-01-02 03:04:05Z,\n" +
+				"3,\"\\.\",,,2022-01-02 03:04:05Z,\\x\n",
+		},
+	}
+	for _, tc := range tests {
+		var b strings.Builder
+		n, err := WriteCopy(&b, rows, &CopyOptions{Format: tc.format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Errorf("%s: expected 3 rows, got %v instead", tc.format, n)
+		}
+		if b.String() != tc.want {
+			t.Errorf("%s: expected %q, got %q instead", tc.format, tc.want, b.String())
+		}
+	}
+
+	var b strings.Builder
+	if _, err := WriteCopy(&b, rows, &CopyOptions{Format: "binary"}); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
+
+func TestCopyFromStatement(t *testing.T) {
+	got, err := CopyFromStatement[copyRow]("events", &CopyOptions{Format: CopyCSV, Columns: []string{"id", "name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `COPY "events" ("id", "name") FROM STDIN (FORMAT csv)`; got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+	got, err = CopyFromStatement[*copyRow]("events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `COPY "events" ("id", "name", "tags", "note", "created", "data") FROM STDIN (FORMAT text)`; got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
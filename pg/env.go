@@ -0,0 +1,88 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultEnvPrefix is the prefix of the libpq environment variables read by
+// ConfigFromEnv.
+const DefaultEnvPrefix = "PG"
+
+// ConfigFromEnv builds a ConnectionConfig from the standard libpq environment
+// variables PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE, PGSSLMODE,
+// PGAPPNAME and PGCONNECT_TIMEOUT. Unset variables leave their field empty.
+// Like libpq, PGHOST and PGPORT may list several comma separated servers.
+// See more at https://www.postgresql.org/docs/13/libpq-envars.html.
+func ConfigFromEnv() (*ConnectionConfig, error) {
+	return ConfigFromEnvWithPrefix(DefaultEnvPrefix)
+}
+
+// ConfigFromEnvWithPrefix builds a ConnectionConfig like ConfigFromEnv, with
+// prefix in place of "PG". For example, a prefix of "POSTGRES_" reads
+// POSTGRES_HOST, POSTGRES_PORT and so on. Since container images commonly
+// use it, <prefix>DB is read when <prefix>DATABASE is not set.
+//
+// Instead of stopping at the first problem, the returned error joins an error
+// for every variable that could not be parsed and every field that fails
+// Validate, each wrapping ErrInvalidConfig.
+func ConfigFromEnvWithPrefix(prefix string) (*ConnectionConfig, error) {
+	var errs []error
+	lookup := func(suffix string) (string, string) {
+		name := prefix + suffix
+		return name, os.Getenv(name)
+	}
+	atoi := func(suffix string) int {
+		name, v := lookup(suffix)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s is not a number: %q", ErrInvalidConfig, name, v))
+		}
+		return n
+	}
+
+	cfg := &ConnectionConfig{}
+	_, hosts := lookup("HOST")
+	portName, ports := lookup("PORT")
+	if err := cfg.setAddrs(hosts, ports); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, portName, err))
+	}
+	_, cfg.User = lookup("USER")
+	_, cfg.Password = lookup("PASSWORD")
+	if _, cfg.Database = lookup("DATABASE"); cfg.Database == "" {
+		_, cfg.Database = lookup("DB")
+	}
+	_, sslMode := lookup("SSLMODE")
+	cfg.SSLMode = SSLMode(sslMode)
+	_, cfg.ApplicationName = lookup("APPNAME")
+	cfg.ConnectTimeout = atoi("CONNECT_TIMEOUT")
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("PGHOST", "db.internal")
+	t.Setenv("PGPORT", "6543")
+	t.Setenv("PGUSER", "app")
+	t.Setenv("PGPASSWORD", "secret")
+	t.Setenv("PGDATABASE", "orders")
+	t.Setenv("PGSSLMODE", "verify-full")
+	t.Setenv("PGAPPNAME", "rig")
+	t.Setenv("PGCONNECT_TIMEOUT", "10")
+
+	got, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := &ConnectionConfig{
+		Host:            "db.internal",
+		Port:            6543,
+		User:            "app",
+		Password:        "secret",
+		Database:        "orders",
+		SSLMode:         SSLModeVerifyFull,
+		ApplicationName: "rig",
+		ConnectTimeout:  10,
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %#v, got %#v instead", want, got)
+	}
+}
+
+func TestConfigFromEnvWithPrefix(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", pg)
+	t.Setenv("POSTGRES_DB", pg)
+
+	got, err := ConfigFromEnvWithPrefix("POSTGRES_")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got.Host != "localhost" || got.Port != 5432 || got.User != pg || got.Database != pg {
+		t.Errorf("unexpected config %#v", got)
+	}
+}
+
+func TestConfigFromEnv_AggregatesErrors(t *testing.T) {
+	t.Setenv("RIGTEST_PORT", "not-a-port")
+	t.Setenv("RIGTEST_CONNECT_TIMEOUT", "-5")
+	t.Setenv("RIGTEST_SSLMODE", "sometimes")
+
+	cfg, err := ConfigFromEnvWithPrefix("RIGTEST_")
+	if cfg != nil || !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v instead", err)
+	}
+	for _, want := range []string{"RIGTEST_PORT", "connect_timeout", "sslmode"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %v, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDriverID makes the name of every registered fakeDriver unique, as
+// database/sql does not allow drivers to be unregistered.
+var fakeDriverID int64
+
+// fakeDriver is a database/sql driver that records the statements it is
+// given, and answers them with the hooks set by a test.
+type fakeDriver struct {
+	mu     sync.Mutex
+	dsns   []string
+	log    []string
+	open   func(dsn string) error
+	ping   func(dsn string) error
+	begin  func(dsn string, opts driver.TxOptions) error
+	commit func(dsn string) error
+	exec   func(dsn, query string, args []driver.NamedValue) (driver.Result, error)
+	query  func(dsn, query string, args []driver.NamedValue) (driver.Rows, error)
+}
+
+// newFakeDriver registers a new fakeDriver and returns it with the name it
+// was registered under.
+func newFakeDriver(t *testing.T) (*fakeDriver, string) {
+	t.Helper()
+	d := &fakeDriver{}
+	name := fmt.Sprintf("pgfake%d", atomic.AddInt64(&fakeDriverID, 1))
+	sql.Register(name, d)
+	return d, name
+}
+
+// Open implements driver.Driver.
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsns = append(d.dsns, dsn)
+	if d.open != nil {
+		if err := d.open(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return &fakeConn{d: d, dsn: dsn}, nil
+}
+
+// record appends a statement to the driver log.
+func (d *fakeDriver) record(stmt string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, stmt)
+}
+
+// statements returns a copy of the driver log.
+func (d *fakeDriver) statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.log...)
+}
+
+// fakeConn is a connection opened by fakeDriver.
+type fakeConn struct {
+	d   *fakeDriver
+	dsn string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("pgfake: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.record("BEGIN")
+	if c.d.begin != nil {
+		if err := c.d.begin(c.dsn, opts); err != nil {
+			return nil, err
+		}
+	}
+	return &fakeTx{c: c}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	if c.d.ping != nil {
+		return c.d.ping(c.dsn)
+	}
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(query)
+	if c.d.exec != nil {
+		return c.d.exec(c.dsn, query, args)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(query)
+	if c.d.query != nil {
+		return c.d.query(c.dsn, query, args)
+	}
+	return &fakeRows{}, nil
+}
+
+// fakeTx is a transaction started on a fakeConn.
+type fakeTx struct {
+	c *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.c.d.record("COMMIT")
+	if tx.c.d.commit != nil {
+		return tx.c.d.commit(tx.c.dsn)
+	}
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.c.d.record("ROLLBACK")
+	return nil
+}
+
+// opened returns a copy of the DSNs the driver was opened with.
+func (d *fakeDriver) opened() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.dsns...)
+}
+
+// fakeRows is a static result set returned by a fakeDriver query hook.
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
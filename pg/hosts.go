@@ -0,0 +1,89 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// HostPort is a server of a ConnectionConfig with several hosts.
+type HostPort struct {
+	Host string `json:"host" yaml:"host"`
+	// Port is the port of the server, zero uses the default port.
+	Port int `json:"port,omitempty" yaml:"port,omitempty"`
+}
+
+// String formats the HostPort as host:port, omitting a zero port and
+// bracketing IPv6 addresses.
+func (h HostPort) String() string {
+	switch {
+	case h.Port != 0:
+		return net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+	case strings.Contains(h.Host, ":"):
+		return "[" + h.Host + "]"
+	default:
+		return h.Host
+	}
+}
+
+// Addrs returns the servers of the ConnectionConfig in the order they are
+// tried: Hosts if it is set, and otherwise Host and Port.
+func (c *ConnectionConfig) Addrs() []HostPort {
+	if len(c.Hosts) > 0 {
+		return append([]HostPort{}, c.Hosts...)
+	}
+	return []HostPort{{Host: c.Host, Port: c.Port}}
+}
+
+// setAddrs sets the servers of the ConnectionConfig from the comma separated
+// host and port parameters. Like libpq, a single port applies to every host,
+// and otherwise there must be one port for each host.
+func (c *ConnectionConfig) setAddrs(hosts, ports string) error {
+	hostList := strings.Split(hosts, ",")
+	portList := strings.Split(ports, ",")
+	if len(portList) != 1 && len(portList) != len(hostList) {
+		return fmt.Errorf("%w: %d ports given for %d hosts", ErrInvalidDSN, len(portList), len(hostList))
+	}
+
+	addrs := make([]HostPort, len(hostList))
+	for i, host := range hostList {
+		port := portList[0]
+		if len(portList) > 1 {
+			port = portList[i]
+		}
+		addrs[i].Host = host
+		if port == "" {
+			continue
+		}
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("%w: invalid port %q", ErrInvalidDSN, port)
+		}
+		addrs[i].Port = n
+	}
+
+	c.Host, c.Port, c.Hosts = "", 0, nil
+	if len(addrs) == 1 {
+		c.Host, c.Port = addrs[0].Host, addrs[0].Port
+	} else {
+		c.Hosts = addrs
+	}
+	return nil
+}
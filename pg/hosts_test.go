@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDSN_MultipleHosts(t *testing.T) {
+	want := &ConnectionConfig{
+		Hosts: []HostPort{
+			{Host: "primary.internal", Port: 5432},
+			{Host: "::1", Port: 5433},
+			{Host: "replica.internal"},
+		},
+		Database:           "orders",
+		TargetSessionAttrs: TargetSessionAttrsReadWrite,
+	}
+
+	for _, dsn := range []string{
+		"postgres://primary.internal:5432,[::1]:5433,replica.internal/orders?target_session_attrs=read-write",
+		"host=primary.internal,::1,replica.internal port=5432,5433, dbname=orders target_session_attrs=read-write",
+	} {
+		got, err := ParseDSN(dsn)
+		if err != nil {
+			t.Fatalf("%v: unexpected error %v", dsn, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%v: expected %#v, got %#v instead", dsn, want, got)
+		}
+	}
+
+	got, err := ParseDSN(want.URL())
+	if err != nil || !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %v to round trip, got %#v with error %v", want.URL(), got, err)
+	}
+	wantODBC := "host=primary.internal,::1,replica.internal port=5432,5433,5432 dbname=orders target_session_attrs=read-write"
+	if got := want.ODBC(); got != wantODBC {
+		t.Errorf("expected %v, got %v instead", wantODBC, got)
+	}
+}
+
+func TestParseDSN_SinglePortForAllHosts(t *testing.T) {
+	got, err := ParseDSN("host=a,b port=6432")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []HostPort{{Host: "a", Port: 6432}, {Host: "b", Port: 6432}}
+	if !reflect.DeepEqual(want, got.Addrs()) {
+		t.Errorf("expected %v, got %v instead", want, got.Addrs())
+	}
+
+	if _, err := ParseDSN("host=a,b,c port=1,2"); !errors.Is(err, ErrInvalidDSN) {
+		t.Errorf("expected ErrInvalidDSN for mismatched ports, got %v", err)
+	}
+}
+
+func TestConfigFromEnv_MultipleHosts(t *testing.T) {
+	t.Setenv("RIGHOSTS_HOST", "a,b")
+	t.Setenv("RIGHOSTS_PORT", "5432,5433")
+	cfg, err := ConfigFromEnvWithPrefix("RIGHOSTS_")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []HostPort{{Host: "a", Port: 5432}, {Host: "b", Port: 5433}}
+	if !reflect.DeepEqual(want, cfg.Hosts) {
+		t.Errorf("expected %v, got %v instead", want, cfg.Hosts)
+	}
+}
+
+func TestHostPort_String(t *testing.T) {
+	for want, h := range map[string]HostPort{
+		"db:5432":    {Host: "db", Port: 5432},
+		"db":         {Host: "db"},
+		"[::1]:5432": {Host: "::1", Port: 5432},
+		"[::1]":      {Host: "::1"},
+	} {
+		if got := h.String(); got != want {
+			t.Errorf("expected %v, got %v instead", want, got)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Hstore is a Postgres hstore, a set of keys with string or NULL values,
+// which implements sql.Scanner and driver.Valuer with the hstore text
+// format, such as "a"=>"1", "b"=>NULL.
+// See more at https://www.postgresql.org/docs/13/hstore.html.
+type Hstore map[string]*string
+
+// Scan implements sql.Scanner. A NULL hstore is scanned into a nil Hstore.
+func (h *Hstore) Scan(src any) error {
+	text, ok, err := srcText(src)
+	if err != nil || !ok {
+		*h = nil
+		return err
+	}
+	out := Hstore{}
+	s := strings.TrimSpace(text)
+	for s != "" {
+		key, rest, err := readHstoreToken(s, "=")
+		if err != nil || key == nil {
+			return fmt.Errorf("pg: invalid hstore %q", text)
+		}
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if !strings.HasPrefix(rest, "=>") {
+			return fmt.Errorf(`pg: invalid hstore %q: expected "=>"`, text)
+		}
+		value, rest, err := readHstoreToken(strings.TrimLeftFunc(rest[2:], unicode.IsSpace), ",")
+		if err != nil {
+			return fmt.Errorf("pg: invalid hstore %q: %w", text, err)
+		}
+		out[*key] = value
+
+		s = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+		if s[0] != ',' {
+			return fmt.Errorf("pg: invalid hstore %q: expected a comma", text)
+		}
+		s = strings.TrimLeftFunc(s[1:], unicode.IsSpace)
+	}
+	*h = out
+	return nil
+}
+
+// Value implements driver.Valuer, writing the keys in sorted order. A nil
+// Hstore is NULL.
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteText(k))
+		b.WriteString("=>")
+		if v := h[k]; v != nil {
+			b.WriteString(quoteText(*v))
+		} else {
+			b.WriteString("NULL")
+		}
+	}
+	return b.String(), nil
+}
+
+// readHstoreToken reads the quoted or unquoted key or value at the start of
+// s, which ends at whitespace or one of the stop characters, and returns it
+// with the rest of s. An unquoted NULL is returned as nil.
+func readHstoreToken(s, stop string) (*string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		token, rest, err := readQuoted(s, false)
+		return &token, rest, err
+	}
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(stop, r)
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	if end == 0 {
+		return nil, s, fmt.Errorf("missing key or value")
+	}
+	token := s[:end]
+	if strings.EqualFold(token, "NULL") {
+		return nil, s[end:], nil
+	}
+	return &token, s[end:], nil
+}
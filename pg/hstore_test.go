@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHstore_Scan(t *testing.T) {
+	var h Hstore
+	if err := h.Scan(`"a"=>"1", b => NULL,"c \"d\""=>"e\\f",g=>h`); err != nil {
+		t.Fatal(err)
+	}
+	want := Hstore{"a": ptr("1"), "b": nil, `c "d"`: ptr(`e\f`), "g": ptr("h")}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("expected %v, got %v instead", want, h)
+	}
+
+	if err := h.Scan(""); err != nil || h == nil || len(h) != 0 {
+		t.Errorf("expected an empty hstore, got %v, %v instead", h, err)
+	}
+	if err := h.Scan(nil); err != nil || h != nil {
+		t.Errorf("expected NULL to scan into a nil hstore, got %v, %v instead", h, err)
+	}
+	for _, invalid := range []string{`"a"`, `"a"=>`, `"a"=>"1" "b"=>"2"`, `NULL=>"1"`, `"a=>"1"`} {
+		if err := h.Scan(invalid); err == nil {
+			t.Errorf("expected %q to be rejected, got %v instead", invalid, h)
+		}
+	}
+}
+
+func TestHstore_Value(t *testing.T) {
+	h := Hstore{"b": nil, "a": ptr(`x"y`)}
+	got, err := h.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"a"=>"x\"y", "b"=>NULL`; got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+	if got, _ := Hstore(nil).Value(); got != nil {
+		t.Errorf("expected a nil hstore to be NULL, got %v instead", got)
+	}
+
+	var back Hstore
+	roundTrip(t, h, &back)
+	if !reflect.DeepEqual(h, back) {
+		t.Errorf("expected %v, got %v instead", h, back)
+	}
+}
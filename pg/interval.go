@@ -0,0 +1,189 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is a Postgres interval. Like in Postgres, months, days and
+// microseconds are kept apart, since the length of a month or a day depends
+// on the date it is added to.
+// See more at https://www.postgresql.org/docs/13/datatype-datetime.html#DATATYPE-INTERVAL-INPUT.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+	// Null is set for a NULL interval, which Value writes back as NULL.
+	Null bool
+}
+
+// Duration returns the interval as a time.Duration, counting a day as 24
+// hours and a month as 30 days, like the justify_interval function.
+func (i Interval) Duration() time.Duration {
+	days := int64(i.Months)*30 + int64(i.Days)
+	return time.Duration(days)*24*time.Hour + time.Duration(i.Microseconds)*time.Microsecond
+}
+
+// String formats the interval in the default postgres IntervalStyle, such as
+// "1 year 2 mons -3 days +04:05:06.5".
+func (i Interval) String() string {
+	var parts []string
+	unit := func(n int64, name string) {
+		if n == 0 {
+			return
+		}
+		if n != 1 {
+			name += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", n, name))
+	}
+	unit(int64(i.Months/12), "year")
+	unit(int64(i.Months%12), "mon")
+	unit(int64(i.Days), "day")
+
+	if i.Microseconds != 0 || len(parts) == 0 {
+		sign, us := "", i.Microseconds
+		if us < 0 {
+			sign, us = "-", -us
+		} else if i.Months < 0 || i.Days < 0 {
+			sign = "+"
+		}
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, us/3_600_000_000, us/60_000_000%60, us/1_000_000%60)
+		if frac := us % 1_000_000; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Scan implements sql.Scanner, reading intervals in the postgres and
+// postgres_verbose IntervalStyle. A NULL interval is scanned into the zero
+// Interval.
+func (i *Interval) Scan(src any) error {
+	text, ok, err := srcText(src)
+	if err != nil || !ok {
+		*i = Interval{Null: err == nil}
+		return err
+	}
+	out, err := parseInterval(text)
+	if err != nil {
+		return fmt.Errorf("pg: invalid interval %q: %w", text, err)
+	}
+	*i = out
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (i Interval) Value() (driver.Value, error) {
+	if i.Null {
+		return nil, nil
+	}
+	return i.String(), nil
+}
+
+// parseInterval parses an interval in the postgres or postgres_verbose
+// IntervalStyle.
+func parseInterval(text string) (Interval, error) {
+	var out Interval
+	fields := strings.Fields(text)
+	if len(fields) > 0 && fields[0] == "@" {
+		fields = fields[1:]
+	}
+	ago := len(fields) > 0 && fields[len(fields)-1] == "ago"
+	if ago {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		return out, fmt.Errorf("no fields")
+	}
+
+	for len(fields) > 0 {
+		f := fields[0]
+		if strings.Contains(f, ":") {
+			us, err := parseClock(f)
+			if err != nil {
+				return out, err
+			}
+			out.Microseconds += us
+			fields = fields[1:]
+			continue
+		}
+		if len(fields) < 2 {
+			return out, fmt.Errorf("missing unit after %q", f)
+		}
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return out, fmt.Errorf("invalid number %q", f)
+		}
+		switch strings.TrimSuffix(fields[1], "s") {
+		case "year":
+			out.Months += int32(n * 12)
+		case "mon":
+			out.Months += int32(n)
+		case "day":
+			out.Days += int32(n)
+		case "hour":
+			out.Microseconds += int64(math.Round(n * 3_600_000_000))
+		case "min":
+			out.Microseconds += int64(math.Round(n * 60_000_000))
+		case "sec":
+			out.Microseconds += int64(math.Round(n * 1_000_000))
+		default:
+			return out, fmt.Errorf("unknown unit %q", fields[1])
+		}
+		fields = fields[2:]
+	}
+	if ago {
+		out = Interval{Months: -out.Months, Days: -out.Days, Microseconds: -out.Microseconds}
+	}
+	return out, nil
+}
+
+// parseClock parses the [-+]HH:MM:SS[.ffffff] time of an interval into
+// microseconds.
+func parseClock(s string) (int64, error) {
+	sign := int64(1)
+	clock := s
+	if strings.HasPrefix(clock, "-") {
+		sign, clock = -1, clock[1:]
+	} else {
+		clock = strings.TrimPrefix(clock, "+")
+	}
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hours, err1 := strconv.ParseInt(parts[0], 10, 64)
+	minutes, err2 := strconv.ParseInt(parts[1], 10, 64)
+	var seconds float64
+	var err3 error
+	if len(parts) == 3 {
+		seconds, err3 = strconv.ParseFloat(parts[2], 64)
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	us := hours*3_600_000_000 + minutes*60_000_000 + int64(math.Round(seconds*1_000_000))
+	return sign * us, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterval_Scan(t *testing.T) {
+	type test struct {
+		text    string
+		want    Interval
+		wantErr bool
+	}
+	tests := []test{
+		{text: "00:00:00", want: Interval{}},
+		{text: "1 year 2 mons 3 days 04:05:06.5", want: Interval{Months: 14, Days: 3, Microseconds: 14706500000}},
+		{text: "-1 days +02:00:00", want: Interval{Days: -1, Microseconds: 7200000000}},
+		{text: "1 day -00:00:00.000001", want: Interval{Days: 1, Microseconds: -1}},
+		{text: "-2 years -3 mons", want: Interval{Months: -27}},
+		{text: "123:00:00", want: Interval{Microseconds: 123 * 3600000000}},
+		{text: "@ 1 hour 2 mins 3.5 secs", want: Interval{Microseconds: 3723500000}},
+		{text: "@ 3 days ago", want: Interval{Days: -3}},
+		{text: "", wantErr: true},
+		{text: "3 fortnights", wantErr: true},
+		{text: "1:2:3:4", wantErr: true},
+		{text: "3", wantErr: true},
+	}
+	for _, tc := range tests {
+		var got Interval
+		err := got.Scan(tc.text)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: expected error %v, got %v instead", tc.text, tc.wantErr, err)
+			continue
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Errorf("%q: expected %+v, got %+v instead", tc.text, tc.want, got)
+		}
+	}
+}
+
+func TestInterval_String(t *testing.T) {
+	type test struct {
+		i    Interval
+		want string
+	}
+	tests := []test{
+		{Interval{}, "00:00:00"},
+		{Interval{Months: 14, Days: 3, Microseconds: 14706500000}, "1 year 2 mons 3 days 04:05:06.5"},
+		{Interval{Days: -1, Microseconds: 7200000000}, "-1 days +02:00:00"},
+		{Interval{Months: 1, Days: 1}, "1 mon 1 day"},
+		{Interval{Months: -27}, "-2 years -3 mons"},
+		{Interval{Microseconds: -1}, "-00:00:00.000001"},
+	}
+	for _, tc := range tests {
+		if got := tc.i.String(); got != tc.want {
+			t.Errorf("expected %v, got %v instead", tc.want, got)
+		}
+		var back Interval
+		if err := back.Scan(tc.want); err != nil || back != tc.i {
+			t.Errorf("expected %q to round trip, got %+v, %v instead", tc.want, back, err)
+		}
+	}
+}
+
+func TestInterval_Duration(t *testing.T) {
+	i := Interval{Months: 1, Days: 2, Microseconds: 3000000}
+	want := 32*24*time.Hour + 3*time.Second
+	if got := i.Duration(); got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONB is a Postgres json or jsonb value decoded into a T, which implements
+// sql.Scanner and driver.Valuer with encoding/json.
+type JSONB[T any] struct {
+	V T
+	// Null is set for a NULL value, which Value writes back as NULL rather
+	// than the JSON null.
+	Null bool
+}
+
+// Scan implements sql.Scanner. A NULL value is scanned into the "default"
+// value of type T, with Null set.
+func (j *JSONB[T]) Scan(src any) error {
+	text, ok, err := srcText(src)
+	if err != nil || !ok {
+		*j = JSONB[T]{Null: err == nil}
+		return err
+	}
+	var v T
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return fmt.Errorf("pg: scan jsonb: %w", err)
+	}
+	j.V, j.Null = v, false
+	return nil
+}
+
+// Value implements driver.Valuer. The JSON document is sent as text, since
+// some drivers send []byte parameters as bytea.
+func (j JSONB[T]) Value() (driver.Value, error) {
+	if j.Null {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, fmt.Errorf("pg: jsonb: %w", err)
+	}
+	return string(b), nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the value as V itself, or
+// as null if Null is set.
+func (j JSONB[T]) MarshalJSON() ([]byte, error) {
+	if j.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(j.V)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the value into V.
+func (j *JSONB[T]) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &j.V)
+}
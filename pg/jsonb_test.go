@@ -0,0 +1,62 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type jsonbDoc struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestJSONB(t *testing.T) {
+	in := JSONB[jsonbDoc]{V: jsonbDoc{Name: "a", Tags: []string{"x"}}}
+	v, err := in.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name":"a","tags":["x"]}`; v != want {
+		t.Errorf("expected %v, got %v instead", want, v)
+	}
+
+	var out JSONB[jsonbDoc]
+	roundTrip(t, in, &out)
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %v, got %v instead", in, out)
+	}
+	if err := out.Scan([]byte(`{"name":"b"}`)); err != nil || out.V.Name != "b" || out.V.Tags != nil {
+		t.Errorf("expected a fresh value to be scanned, got %+v, %v instead", out, err)
+	}
+	if err := out.Scan(nil); err != nil || !reflect.DeepEqual(out, JSONB[jsonbDoc]{Null: true}) {
+		t.Errorf("expected NULL to scan into the zero value, got %+v, %v instead", out, err)
+	}
+	if err := out.Scan("{"); err == nil {
+		t.Error("expected invalid JSON to be rejected")
+	}
+
+	b, err := json.Marshal(struct{ Doc JSONB[jsonbDoc] }{in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Doc":{"name":"a","tags":["x"]}}`; string(b) != want {
+		t.Errorf("expected %v, got %v instead", want, string(b))
+	}
+}
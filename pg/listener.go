@@ -0,0 +1,493 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bradleybonitatibus/rig/pg/pgerr"
+)
+
+const (
+	// DefaultListenerBaseBackoff is the backoff before the first reconnect,
+	// when ListenerOptions.BaseBackoff is not set.
+	DefaultListenerBaseBackoff = 100 * time.Millisecond
+	// DefaultListenerMaxBackoff caps the backoff between reconnects, when
+	// ListenerOptions.MaxBackoff is not set.
+	DefaultListenerMaxBackoff = 30 * time.Second
+	// DefaultListenerBufferSize is the number of notifications buffered for
+	// each subscription, when ListenerOptions.BufferSize is not set.
+	DefaultListenerBufferSize = 64
+)
+
+// ErrListenerClosed is returned when subscribing to a closed Listener.
+var ErrListenerClosed = errors.New("pg: listener is closed")
+
+// Notification is a notification received by a Listener.
+type Notification struct {
+	// Channel is the channel the notification was sent on.
+	Channel string
+	// Payload is the payload given to NOTIFY, which may be empty.
+	Payload string
+	// Reconnected is set on the notification a Listener sends to every
+	// subscription after it reconnected and listened to the channel again.
+	// Notifications sent while the Listener was disconnected are lost, so
+	// subscribers should resync their state when they receive it.
+	Reconnected bool
+}
+
+// NotificationConn is a connection that receives notifications. It is
+// implemented on top of a driver, such as with the WaitForNotification method
+// of a pgx connection or the Notify channel of a lib/pq ListenerConn.
+type NotificationConn interface {
+	// Listen runs LISTEN for channel.
+	Listen(ctx context.Context, channel string) error
+	// Unlisten runs UNLISTEN for channel.
+	Unlisten(ctx context.Context, channel string) error
+	// WaitForNotification blocks until a notification is received. When ctx
+	// is done, it returns ctx.Err() and the connection must remain usable.
+	// Any other error is treated as the connection being lost.
+	WaitForNotification(ctx context.Context) (Notification, error)
+	// Close closes the connection.
+	Close() error
+}
+
+// NotificationSource opens the connections of a Listener.
+type NotificationSource interface {
+	Connect(ctx context.Context) (NotificationConn, error)
+}
+
+// NotificationSourceFunc is a function that implements NotificationSource.
+type NotificationSourceFunc func(ctx context.Context) (NotificationConn, error)
+
+// Connect calls f.
+func (f NotificationSourceFunc) Connect(ctx context.Context) (NotificationConn, error) {
+	return f(ctx)
+}
+
+// ListenerOptions configures a Listener.
+type ListenerOptions struct {
+	// BaseBackoff is the backoff before the first reconnect, which doubles
+	// with every failed attempt up to MaxBackoff. A random jitter of up to
+	// the backoff is used, so that listeners do not reconnect in lock step.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BufferSize is the number of notifications buffered for each
+	// subscription, defaulting to DefaultListenerBufferSize.
+	BufferSize int
+	// OnError is called with the error of every failed connection attempt and
+	// every lost connection, such as for logging. It may be nil.
+	OnError func(error)
+}
+
+// Listener receives notifications on a single connection, and fans them out
+// to the subscriptions of their channel. When the connection is lost, the
+// Listener reconnects with a jittered backoff, listens to every subscribed
+// channel again, and sends a Notification with Reconnected set to every
+// subscription.
+//
+// Notifications are delivered to each subscription in order. A subscription
+// that falls more than BufferSize notifications behind blocks the delivery to
+// every other subscription until it catches up.
+type Listener struct {
+	source NotificationSource
+	opts   ListenerOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// subscribeMu serializes Subscribe, Handle and Unsubscribe, so that a
+	// channel is only listened to or unlistened by one of them at a time.
+	subscribeMu sync.Mutex
+
+	mu        sync.Mutex
+	subs      map[string][]*Subscription
+	connected bool
+	requests  []*listenRequest
+	interrupt context.CancelFunc
+	closeErr  error
+}
+
+// listenRequest asks the Listener goroutine to listen to or unlisten a
+// channel on the current connection.
+type listenRequest struct {
+	channel string
+	listen  bool
+	err     chan error
+}
+
+// Subscription is a subscription to a channel of a Listener, created by
+// Subscribe or Handle.
+type Subscription struct {
+	// C receives the notifications of the channel. It is nil for
+	// subscriptions created by Handle, and like time.Ticker.C it is not
+	// closed by Unsubscribe.
+	C <-chan Notification
+
+	l       *Listener
+	channel string
+	ch      chan Notification
+	done    chan struct{}
+}
+
+// NewListener creates a Listener over source, which connects and receives
+// notifications in the background until Close is called.
+func NewListener(source NotificationSource, opts ListenerOptions) *Listener {
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = DefaultListenerBaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultListenerMaxBackoff
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultListenerBufferSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		source: source,
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		subs:   map[string][]*Subscription{},
+	}
+	go l.run()
+	return l
+}
+
+// Subscribe subscribes to channel, whose notifications are received on the C
+// field of the returned Subscription. The first subscription to a channel
+// runs LISTEN before returning, unless the Listener is not connected, in
+// which case the channel is listened to when it reconnects.
+func (l *Listener) Subscribe(ctx context.Context, channel string) (*Subscription, error) {
+	l.subscribeMu.Lock()
+	defer l.subscribeMu.Unlock()
+	return l.subscribe(ctx, channel)
+}
+
+// Handle subscribes to channel like Subscribe, and calls fn with every
+// notification of the channel from a goroutine of the subscription, until
+// it is unsubscribed.
+func (l *Listener) Handle(ctx context.Context, channel string, fn func(Notification)) (*Subscription, error) {
+	l.subscribeMu.Lock()
+	defer l.subscribeMu.Unlock()
+	s, err := l.subscribe(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	s.C = nil
+	go func() {
+		for {
+			select {
+			case n := <-s.ch:
+				fn(n)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Close stops the Listener, closes its connection and ends every
+// subscription.
+func (l *Listener) Close() error {
+	l.cancel()
+	<-l.done
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, subs := range l.subs {
+		for _, s := range subs {
+			close(s.done)
+		}
+	}
+	l.subs = map[string][]*Subscription{}
+	err := l.closeErr
+	l.closeErr = nil
+	return err
+}
+
+// Channel returns the channel of s.
+func (s *Subscription) Channel() string {
+	return s.channel
+}
+
+// Unsubscribe ends s. The last subscription to a channel runs UNLISTEN before
+// returning, if the Listener is connected. Unsubscribing more than once, or
+// after the Listener is closed, does nothing.
+func (s *Subscription) Unsubscribe(ctx context.Context) error {
+	l := s.l
+	l.subscribeMu.Lock()
+	defer l.subscribeMu.Unlock()
+	removed, last := l.remove(s)
+	if !removed || !last {
+		return nil
+	}
+	if err := l.request(ctx, s.channel, false); err != nil {
+		return fmt.Errorf("pg: unlisten %q: %w", s.channel, err)
+	}
+	return nil
+}
+
+// subscribe adds a subscription to channel, listening to it if it is the
+// first one. It must be called with subscribeMu held.
+func (l *Listener) subscribe(ctx context.Context, channel string) (*Subscription, error) {
+	s := &Subscription{
+		l:       l,
+		channel: channel,
+		ch:      make(chan Notification, l.opts.BufferSize),
+		done:    make(chan struct{}),
+	}
+	s.C = s.ch
+
+	l.mu.Lock()
+	if l.ctx.Err() != nil {
+		l.mu.Unlock()
+		return nil, ErrListenerClosed
+	}
+	first := len(l.subs[channel]) == 0
+	l.subs[channel] = append(l.subs[channel], s)
+	l.mu.Unlock()
+
+	if first {
+		if err := l.request(ctx, channel, true); err != nil {
+			l.remove(s)
+			return nil, fmt.Errorf("pg: listen %q: %w", channel, err)
+		}
+	}
+	return s, nil
+}
+
+// remove removes s from its channel, and reports whether it was subscribed
+// and whether it was the last subscription to the channel.
+func (l *Listener) remove(s *Subscription) (removed, last bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	subs := l.subs[s.channel]
+	for i, sub := range subs {
+		if sub != s {
+			continue
+		}
+		close(s.done)
+		subs = append(subs[:i:i], subs[i+1:]...)
+		if len(subs) == 0 {
+			delete(l.subs, s.channel)
+			return true, true
+		}
+		l.subs[s.channel] = subs
+		return true, false
+	}
+	return false, false
+}
+
+// request has the Listener goroutine listen to or unlisten channel on the
+// current connection, and waits for the result. It returns nil when the
+// Listener is not connected, since only the subscribed channels are listened
+// to when it reconnects.
+func (l *Listener) request(ctx context.Context, channel string, listen bool) error {
+	l.mu.Lock()
+	if !l.connected {
+		l.mu.Unlock()
+		return nil
+	}
+	r := &listenRequest{channel: channel, listen: listen, err: make(chan error, 1)}
+	l.requests = append(l.requests, r)
+	if l.interrupt != nil {
+		l.interrupt()
+	}
+	l.mu.Unlock()
+
+	select {
+	case err := <-r.err:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.done:
+		return ErrListenerClosed
+	}
+}
+
+// run connects, serves and reconnects until the Listener is closed.
+func (l *Listener) run() {
+	defer close(l.done)
+	backoff := l.opts.BaseBackoff
+	reconnect := false
+	for {
+		conn, err := l.source.Connect(l.ctx)
+		if err != nil {
+			err = fmt.Errorf("pg: listener connect: %w", err)
+		} else if err = l.listenAll(conn); err == nil {
+			backoff = l.opts.BaseBackoff
+			if reconnect {
+				l.dispatchReconnected()
+			}
+			reconnect = true
+			err = l.serve(conn)
+		}
+		if conn != nil {
+			l.disconnect()
+			if closeErr := conn.Close(); closeErr != nil && l.ctx.Err() != nil {
+				l.mu.Lock()
+				l.closeErr = fmt.Errorf("pg: close listener connection: %w", closeErr)
+				l.mu.Unlock()
+			}
+		}
+		if l.ctx.Err() != nil {
+			return
+		}
+		if l.opts.OnError != nil {
+			l.opts.OnError(err)
+		}
+
+		timer := time.NewTimer(time.Duration(rand.Int63n(int64(backoff)) + 1))
+		select {
+		case <-l.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > l.opts.MaxBackoff {
+			backoff = l.opts.MaxBackoff
+		}
+	}
+}
+
+// listenAll marks the Listener as connected, and listens to every subscribed
+// channel on conn.
+func (l *Listener) listenAll(conn NotificationConn) error {
+	l.mu.Lock()
+	l.connected = true
+	channels := make([]string, 0, len(l.subs))
+	for c := range l.subs {
+		channels = append(channels, c)
+	}
+	l.mu.Unlock()
+	sort.Strings(channels)
+	for _, c := range channels {
+		if err := conn.Listen(l.ctx, c); err != nil {
+			return fmt.Errorf("pg: listen %q: %w", c, err)
+		}
+	}
+	return nil
+}
+
+// disconnect marks the Listener as disconnected. The pending requests are
+// answered with nil, since only the subscribed channels are listened to when
+// it reconnects.
+func (l *Listener) disconnect() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.connected = false
+	for _, r := range l.requests {
+		r.err <- nil
+	}
+	l.requests = nil
+}
+
+// serve dispatches the notifications received on conn and answers the
+// requests, until conn is lost or the Listener is closed.
+func (l *Listener) serve(conn NotificationConn) error {
+	for {
+		waitCtx, cancel := context.WithCancel(l.ctx)
+		l.mu.Lock()
+		requests := l.requests
+		l.requests = nil
+		l.interrupt = cancel
+		l.mu.Unlock()
+
+		if len(requests) > 0 {
+			cancel()
+			if err := l.answer(conn, requests); err != nil {
+				return err
+			}
+			continue
+		}
+
+		n, err := conn.WaitForNotification(waitCtx)
+		l.mu.Lock()
+		l.interrupt = nil
+		l.mu.Unlock()
+		interrupted := waitCtx.Err() != nil
+		cancel()
+		if err != nil {
+			if interrupted && l.ctx.Err() == nil {
+				continue
+			}
+			return fmt.Errorf("pg: wait for notification: %w", err)
+		}
+		l.dispatch(n)
+	}
+}
+
+// answer runs the LISTEN and UNLISTEN of requests on conn. A request failing
+// because conn is lost is answered with nil, since only the subscribed
+// channels are listened to when the Listener reconnects, and the connection
+// error is returned.
+func (l *Listener) answer(conn NotificationConn, requests []*listenRequest) error {
+	for i, r := range requests {
+		var err error
+		if r.listen {
+			err = conn.Listen(l.ctx, r.channel)
+		} else {
+			err = conn.Unlisten(l.ctx, r.channel)
+		}
+		if pgerr.IsConnectionError(err) {
+			for _, r := range requests[i:] {
+				r.err <- nil
+			}
+			return fmt.Errorf("pg: listen %q: %w", r.channel, err)
+		}
+		r.err <- err
+	}
+	return nil
+}
+
+// dispatch sends n to every subscription of its channel.
+func (l *Listener) dispatch(n Notification) {
+	l.mu.Lock()
+	subs := append([]*Subscription(nil), l.subs[n.Channel]...)
+	l.mu.Unlock()
+	for _, s := range subs {
+		select {
+		case s.ch <- n:
+		case <-s.done:
+		case <-l.ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatchReconnected sends a Notification with Reconnected set to every
+// subscription.
+func (l *Listener) dispatchReconnected() {
+	l.mu.Lock()
+	channels := make([]string, 0, len(l.subs))
+	for c := range l.subs {
+		channels = append(channels, c)
+	}
+	l.mu.Unlock()
+	sort.Strings(channels)
+	for _, c := range channels {
+		l.dispatch(Notification{Channel: c, Reconnected: true})
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotificationConn is a NotificationConn whose notifications are sent by
+// the test, and which records the channels it listens to.
+type fakeNotificationConn struct {
+	log           *[]string
+	mu            *sync.Mutex
+	notifications chan Notification
+	lost          chan struct{}
+	lostOnce      sync.Once
+}
+
+func (c *fakeNotificationConn) record(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.log = append(*c.log, s)
+}
+
+func (c *fakeNotificationConn) Listen(_ context.Context, channel string) error {
+	c.record("LISTEN " + channel)
+	return nil
+}
+
+func (c *fakeNotificationConn) Unlisten(_ context.Context, channel string) error {
+	c.record("UNLISTEN " + channel)
+	return nil
+}
+
+func (c *fakeNotificationConn) WaitForNotification(ctx context.Context) (Notification, error) {
+	select {
+	case n := <-c.notifications:
+		return n, nil
+	case <-c.lost:
+		return Notification{}, errors.New("connection reset by peer")
+	case <-ctx.Done():
+		return Notification{}, ctx.Err()
+	}
+}
+
+func (c *fakeNotificationConn) Close() error {
+	c.record("CLOSE")
+	return nil
+}
+
+// lose makes the connection fail, as if the server went away.
+func (c *fakeNotificationConn) lose() {
+	c.lostOnce.Do(func() { close(c.lost) })
+}
+
+// fakeNotificationSource opens fakeNotificationConns, failing every attempt
+// while down is set.
+type fakeNotificationSource struct {
+	mu    sync.Mutex
+	log   []string
+	down  bool
+	conns chan *fakeNotificationConn
+}
+
+func newFakeNotificationSource() *fakeNotificationSource {
+	return &fakeNotificationSource{conns: make(chan *fakeNotificationConn, 16)}
+}
+
+func (s *fakeNotificationSource) Connect(context.Context) (NotificationConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return nil, errors.New("connection refused")
+	}
+	c := &fakeNotificationConn{
+		log:           &s.log,
+		mu:            &s.mu,
+		notifications: make(chan Notification),
+		lost:          make(chan struct{}),
+	}
+	s.log = append(s.log, "CONNECT")
+	s.conns <- c
+	return c, nil
+}
+
+func (s *fakeNotificationSource) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *fakeNotificationSource) statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.log...)
+}
+
+func (s *fakeNotificationSource) next(t *testing.T) *fakeNotificationConn {
+	t.Helper()
+	select {
+	case c := <-s.conns:
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("expected the listener to connect")
+		return nil
+	}
+}
+
+func receive(t *testing.T, c <-chan Notification) Notification {
+	t.Helper()
+	select {
+	case n := <-c:
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("expected a notification")
+		return Notification{}
+	}
+}
+
+func TestListener_Subscribe(t *testing.T) {
+	src := newFakeNotificationSource()
+	l := NewListener(src, ListenerOptions{})
+	defer l.Close()
+	ctx := context.Background()
+	conn := src.next(t)
+
+	a1, err := l.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := l.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handled := make(chan Notification, 1)
+	if _, err := l.Handle(ctx, "b", func(n Notification) { handled <- n }); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.notifications <- Notification{Channel: "c", Payload: "ignored"}
+	conn.notifications <- Notification{Channel: "a", Payload: "1"}
+	conn.notifications <- Notification{Channel: "b", Payload: "2"}
+	want := Notification{Channel: "a", Payload: "1"}
+	for _, s := range []*Subscription{a1, a2} {
+		if n := receive(t, s.C); n != want {
+			t.Errorf("expected %v, got %v instead", want, n)
+		}
+	}
+	if n := receive(t, handled); n.Payload != "2" {
+		t.Errorf("expected payload 2, got %v instead", n.Payload)
+	}
+
+	if err := a1.Unsubscribe(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a2.Unsubscribe(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a2.Unsubscribe(ctx); err != nil {
+		t.Errorf("expected unsubscribing twice to do nothing, got %v", err)
+	}
+	wantLog := []string{"CONNECT", "LISTEN a", "LISTEN b", "UNLISTEN a"}
+	if got := src.statements(); !reflect.DeepEqual(got, wantLog) {
+		t.Errorf("expected %v, got %v instead", wantLog, got)
+	}
+}
+
+func TestListener_Reconnect(t *testing.T) {
+	src := newFakeNotificationSource()
+	src.setDown(true)
+	errs := make(chan error, 16)
+	l := NewListener(src, ListenerOptions{
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		OnError:     func(err error) { errs <- err },
+	})
+	defer l.Close()
+	ctx := context.Background()
+
+	// Subscribing while disconnected does not wait for the connection.
+	a, err := l.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-errs
+	src.setDown(false)
+	conn := src.next(t)
+	conn.notifications <- Notification{Channel: "a", Payload: "1"}
+	if n := receive(t, a.C); n.Reconnected || n.Payload != "1" {
+		t.Errorf("expected no reconnected event on the first connection, got %v", n)
+	}
+
+	conn.lose()
+	conn = src.next(t)
+	if n := receive(t, a.C); !n.Reconnected || n.Channel != "a" {
+		t.Errorf("expected a reconnected event, got %v instead", n)
+	}
+	conn.notifications <- Notification{Channel: "a", Payload: "2"}
+	if n := receive(t, a.C); n.Payload != "2" {
+		t.Errorf("expected payload 2, got %v instead", n.Payload)
+	}
+
+	wantLog := []string{"CONNECT", "LISTEN a", "CLOSE", "CONNECT", "LISTEN a"}
+	if got := src.statements(); !reflect.DeepEqual(got, wantLog) {
+		t.Errorf("expected %v, got %v instead", wantLog, got)
+	}
+	reported := false
+	for len(errs) > 0 {
+		if err := <-errs; strings.Contains(err.Error(), "connection reset by peer") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Error("expected the lost connection to be reported")
+	}
+}
+
+func TestListener_Close(t *testing.T) {
+	src := newFakeNotificationSource()
+	l := NewListener(src, ListenerOptions{})
+	ctx := context.Background()
+	src.next(t)
+
+	stopped := make(chan struct{})
+	s, err := l.Handle(ctx, "a", func(Notification) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-s.done
+		close(stopped)
+	}()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("expected Close to end the subscriptions")
+	}
+	if _, err := l.Subscribe(ctx, "a"); !errors.Is(err, ErrListenerClosed) {
+		t.Errorf("expected %v, got %v instead", ErrListenerClosed, err)
+	}
+	if err := s.Unsubscribe(ctx); err != nil {
+		t.Errorf("expected unsubscribing after Close to do nothing, got %v", err)
+	}
+	if got := src.statements(); got[len(got)-1] != "CLOSE" {
+		t.Errorf("expected the connection to be closed, got %v", got)
+	}
+}
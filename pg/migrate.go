@@ -0,0 +1,477 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMigrationsTable is the table a Migrator records the applied
+// migrations in, when MigratorOptions.Table is not set.
+const DefaultMigrationsTable = "schema_migrations"
+
+var (
+	// ErrInvalidMigration is returned, wrapped with the reason, when the
+	// migration files cannot be loaded.
+	ErrInvalidMigration = errors.New("pg: invalid migration")
+	// ErrMigrationModified is returned, wrapped with the version, when an
+	// applied migration no longer matches the checksum it was applied with.
+	ErrMigrationModified = errors.New("pg: applied migration was modified")
+	// ErrMigrationMissing is returned, wrapped with the version, when an
+	// applied migration has no migration file.
+	ErrMigrationMissing = errors.New("pg: applied migration is missing")
+	// ErrMigrationOutOfOrder is returned, wrapped with the version, when a
+	// pending migration is older than the latest applied migration.
+	ErrMigrationOutOfOrder = errors.New("pg: migration is out of order")
+	// ErrNoDownMigration is returned, wrapped with the version, when an
+	// applied migration has to be reverted but has no down migration.
+	ErrNoDownMigration = errors.New("pg: migration has no down migration")
+)
+
+// Migration is a versioned schema change, loaded from a pair of
+// <version>_<name>.up.sql and <version>_<name>.down.sql files.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down reverts Up, and is empty if there is no down migration file.
+	Down string
+}
+
+// Checksum returns the hex encoded SHA-256 of the up migration, which is
+// recorded when the migration is applied.
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationStatus is the state of a migration in the database.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is set when the migration file no longer matches the
+	// checksum the migration was applied with.
+	Modified bool
+	// Missing is set when the migration was applied, but there is no
+	// migration file for it.
+	Missing bool
+	// OutOfOrder is set when the migration is pending, but is older than
+	// the latest applied migration.
+	OutOfOrder bool
+}
+
+// MigratorOptions configures a Migrator.
+type MigratorOptions struct {
+	// Table records the applied migrations, and may be schema qualified.
+	// It defaults to DefaultMigrationsTable.
+	Table string
+	// LockKey is the pg_advisory_lock key held while migrating, defaulting
+	// to a hash of Table.
+	LockKey int64
+}
+
+// Migrator applies the migrations of an fs.FS to a database. Every
+// migration runs in its own transaction, together with its record in the
+// migrations table, and a session level advisory lock is held while
+// migrating, so that concurrent deploys migrate one after another.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	table      string
+	lockKey    int64
+}
+
+// appliedMigration is a row of the migrations table.
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator loads the migrations from the top level directory of fsys,
+// which can be a go:embed file system. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, such as
+// 0001_create_users.up.sql; other files are ignored. Every version needs an
+// up migration, while the down migration is optional.
+func NewMigrator(db *sql.DB, fsys fs.FS, opts MigratorOptions) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultMigrationsTable
+	}
+	if opts.LockKey == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(opts.Table))
+		opts.LockKey = int64(h.Sum64())
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		table:      quoteIdentifier(opts.Table),
+		lockKey:    opts.LockKey,
+	}, nil
+}
+
+// Migrations returns the loaded migrations, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration{}, m.migrations...)
+}
+
+// Up applies every pending migration, and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if len(m.migrations) == 0 {
+		return 0, nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the latest applied migration, and returns how many were
+// reverted, which is zero when no migration is applied.
+func (m *Migrator) Down(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn, applied []appliedMigration) error {
+		if len(applied) == 0 {
+			return nil
+		}
+		if err := m.revert(ctx, conn, applied[len(applied)-1].version); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// To applies or reverts migrations until version is the latest applied
+// migration, and returns how many were applied or reverted. A version of
+// zero reverts every migration.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return 0, fmt.Errorf("%w: unknown version %d", ErrInvalidMigration, version)
+	}
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn, applied []appliedMigration) error {
+		for i := len(applied) - 1; i >= 0 && applied[i].version > version; i-- {
+			if err := m.revert(ctx, conn, applied[i].version); err != nil {
+				return err
+			}
+			n++
+		}
+		done := make(map[int64]bool, len(applied))
+		for _, a := range applied {
+			done[a.version] = true
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			if done[mig.Version] {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Status returns the state of every loaded and applied migration, ordered by
+// version. Unlike Up, Down and To, Status reports modified, missing and out
+// of order migrations instead of failing.
+//
+// Status only reads the database, so it can be run by a read-only role. If
+// the migrations table does not exist yet, no migration is applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", m.table).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("pg: migrate: read %s: %w", m.table, err)
+	}
+	if !exists {
+		return m.status(nil), nil
+	}
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+// status merges the loaded migrations with the applied migrations.
+func (m *Migrator) status(applied []appliedMigration) []MigrationStatus {
+	var latest int64
+	byVersion := make(map[int64]appliedMigration, len(applied))
+	for _, a := range applied {
+		byVersion[a.version] = a
+		if a.version > latest {
+			latest = a.version
+		}
+	}
+
+	var statuses []MigrationStatus
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := byVersion[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.appliedAt
+			s.Modified = a.checksum != mig.Checksum()
+			delete(byVersion, mig.Version)
+		} else {
+			s.OutOfOrder = mig.Version < latest
+		}
+		statuses = append(statuses, s)
+	}
+	for _, a := range byVersion {
+		statuses = append(statuses, MigrationStatus{
+			Version:   a.version,
+			Name:      a.name,
+			Applied:   true,
+			AppliedAt: a.appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses
+}
+
+// verify returns the joined errors for every modified, missing and out of
+// order migration.
+func (m *Migrator) verify(applied []appliedMigration) error {
+	var errs []error
+	for _, s := range m.status(applied) {
+		switch {
+		case s.Modified:
+			errs = append(errs, fmt.Errorf("%w: version %d", ErrMigrationModified, s.Version))
+		case s.Missing:
+			errs = append(errs, fmt.Errorf("%w: version %d", ErrMigrationMissing, s.Version))
+		case s.OutOfOrder:
+			errs = append(errs, fmt.Errorf("%w: version %d", ErrMigrationOutOfOrder, s.Version))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// locked runs fn on a connection holding the advisory lock of the Migrator,
+// once the applied migrations are read and verified.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, applied []appliedMigration) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("pg: migrate: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.lockKey); err != nil {
+		return fmt.Errorf("pg: migrate: lock: %w", err)
+	}
+	defer func() {
+		// The lock is released even when ctx is cancelled, as the pooled
+		// connection would otherwise keep holding it.
+		_, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", m.lockKey)
+		if unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("pg: migrate: unlock: %w", unlockErr))
+		}
+	}()
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return err
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(applied); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// apply runs the up migration of mig, and records it as applied.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	err := WithTx(ctx, conn, nil, func(ctx context.Context, tx *Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO "+m.table+" (version, name, checksum) VALUES ($1, $2, $3)",
+			mig.Version, mig.Name, mig.Checksum())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("pg: migrate up %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// revert runs the down migration of version, and removes its record.
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, version int64) error {
+	mig, _ := m.find(version)
+	if mig.Down == "" {
+		return fmt.Errorf("%w: version %d", ErrNoDownMigration, version)
+	}
+	err := WithTx(ctx, conn, nil, func(ctx context.Context, tx *Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM "+m.table+" WHERE version = $1", version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("pg: migrate down %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// find returns the loaded migration with version.
+func (m *Migrator) find(version int64) (Migration, bool) {
+	i := sort.Search(len(m.migrations), func(i int) bool {
+		return m.migrations[i].Version >= version
+	})
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return m.migrations[i], true
+	}
+	return Migration{}, false
+}
+
+// queryExecer is implemented by *sql.DB and *sql.Conn.
+type queryExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// createTable creates the migrations table if it does not exist.
+func (m *Migrator) createTable(ctx context.Context, db queryExecer) error {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+m.table+` (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	checksum text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return fmt.Errorf("pg: migrate: create %s: %w", m.table, err)
+	}
+	return nil
+}
+
+// applied reads the applied migrations, ordered by version.
+func (m *Migrator) applied(ctx context.Context, db queryExecer) ([]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+m.table+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("pg: migrate: read %s: %w", m.table, err)
+	}
+	defer rows.Close()
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("pg: migrate: read %s: %w", m.table, err)
+		}
+		applied = append(applied, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("pg: migrate: read %s: %w", m.table, err)
+	}
+	return applied, nil
+}
+
+// loadMigrations reads the migration files of fsys, ordered by version.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMigration, err)
+	}
+	byVersion := map[int64]*Migration{}
+	// prefixes holds the version prefix of every version, so that 0001_x
+	// and 1_x are not merged into a single migration.
+	prefixes := map[int64]string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		base, up := strings.CutSuffix(e.Name(), ".up.sql")
+		if !up {
+			var down bool
+			if base, down = strings.CutSuffix(e.Name(), ".down.sql"); !down {
+				continue
+			}
+		}
+		v, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s does not start with a positive version", ErrInvalidMigration, e.Name())
+		}
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMigration, err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+			prefixes[version] = v
+		} else if mig.Name != name || prefixes[version] != v {
+			return nil, fmt.Errorf("%w: version %d is used by %s_%s and %s_%s", ErrInvalidMigration, version, prefixes[version], mig.Name, v, name)
+		}
+		if up {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up migration", ErrInvalidMigration, mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// quoteIdentifier quotes every part of a possibly schema qualified
+// identifier.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// migrationFixture is a fakeDriver database that keeps the rows of the
+// migrations table, and the statements of the migrations it ran.
+type migrationFixture struct {
+	d     *fakeDriver
+	mu    sync.Mutex
+	table bool
+	rows  map[int64][]driver.Value
+	ran   []string
+}
+
+func newMigrationFixture(t *testing.T) (*migrationFixture, *sql.DB) {
+	t.Helper()
+	f := &migrationFixture{rows: map[int64][]driver.Value{}}
+	d, name := newFakeDriver(t)
+	f.d = d
+	d.exec = func(_, query string, args []driver.NamedValue) (driver.Result, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch {
+		case strings.HasPrefix(query, `INSERT INTO "schema_migrations"`):
+			v := args[0].Value.(int64)
+			f.rows[v] = []driver.Value{v, args[1].Value, args[2].Value, time.Unix(v, 0)}
+		case strings.HasPrefix(query, `DELETE FROM "schema_migrations"`):
+			delete(f.rows, args[0].Value.(int64))
+		case strings.HasPrefix(query, "CREATE TABLE"):
+			f.table = true
+		case strings.HasPrefix(query, "SELECT pg_advisory"):
+		case query == "FAIL":
+			return nil, errors.New("syntax error")
+		default:
+			f.ran = append(f.ran, query)
+		}
+		return driver.RowsAffected(1), nil
+	}
+	d.query = func(_, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if strings.HasPrefix(query, "SELECT to_regclass") {
+			return &fakeRows{columns: []string{"exists"}, values: [][]driver.Value{{f.table}}}, nil
+		}
+		if !f.table {
+			return nil, errors.New(`relation "schema_migrations" does not exist`)
+		}
+		rows := &fakeRows{columns: []string{"version", "name", "checksum", "applied_at"}}
+		for _, r := range f.rows {
+			rows.values = append(rows.values, r)
+		}
+		sort.Slice(rows.values, func(i, j int) bool {
+			return rows.values[i][0].(int64) < rows.values[j][0].(int64)
+		})
+		return rows, nil
+	}
+	db, err := sql.Open(name, "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return f, db
+}
+
+// statements returns the migration statements run so far, and resets them.
+func (f *migrationFixture) statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ran := f.ran
+	f.ran = nil
+	return ran
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_users.up.sql":    {Data: []byte("CREATE users")},
+		"0001_users.down.sql":  {Data: []byte("DROP users")},
+		"0002_orders.up.sql":   {Data: []byte("CREATE orders")},
+		"0002_orders.down.sql": {Data: []byte("DROP orders")},
+		"0010_index.up.sql":    {Data: []byte("CREATE index")},
+		"README.md":            {Data: []byte("ignored")},
+	}
+}
+
+func TestNewMigrator(t *testing.T) {
+	type test struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []int64
+		wantErr bool
+	}
+	tests := []test{
+		{
+			name: "orders by version",
+			fsys: testMigrations(),
+			want: []int64{1, 2, 10},
+		},
+		{
+			name:    "missing version",
+			fsys:    fstest.MapFS{"users.up.sql": {Data: []byte("x")}},
+			wantErr: true,
+		},
+		{
+			name:    "missing up migration",
+			fsys:    fstest.MapFS{"0001_users.down.sql": {Data: []byte("x")}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate version prefix",
+			fsys: fstest.MapFS{
+				"0001_users.up.sql": {Data: []byte("x")},
+				"1_users.up.sql":    {Data: []byte("y")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_users.up.sql":  {Data: []byte("x")},
+				"0001_orders.up.sql": {Data: []byte("y")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewMigrator(nil, tc.fsys, MigratorOptions{})
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidMigration) {
+					t.Errorf("expected %v, got %v instead", ErrInvalidMigration, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int64
+			for _, mig := range m.Migrations() {
+				got = append(got, mig.Version)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMigrator_UpDownTo(t *testing.T) {
+	f, db := newMigrationFixture(t)
+	m, err := NewMigrator(db, testMigrations(), MigratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	steps := []struct {
+		name string
+		run  func() (int, error)
+		n    int
+		ran  []string
+	}{
+		{"to 2", func() (int, error) { return m.To(ctx, 2) }, 2, []string{"CREATE users", "CREATE orders"}},
+		{"up", func() (int, error) { return m.Up(ctx) }, 1, []string{"CREATE index"}},
+		{"up again", func() (int, error) { return m.Up(ctx) }, 0, nil},
+	}
+	for _, s := range steps {
+		n, err := s.run()
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if n != s.n {
+			t.Errorf("%s: expected %v migrations, got %v instead", s.name, s.n, n)
+		}
+		if got := f.statements(); !reflect.DeepEqual(got, s.ran) {
+			t.Errorf("%s: expected %v, got %v instead", s.name, s.ran, got)
+		}
+	}
+
+	// 0010 has no down migration.
+	if _, err := m.To(ctx, 1); !errors.Is(err, ErrNoDownMigration) {
+		t.Errorf("expected %v, got %v instead", ErrNoDownMigration, err)
+	}
+	f.mu.Lock()
+	delete(f.rows, 10)
+	f.mu.Unlock()
+
+	n, err := m.To(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"DROP orders", "DROP users"}
+	if got := f.statements(); n != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+	if n, err := m.Down(ctx); n != 0 || err != nil {
+		t.Errorf("expected nothing to revert, got %v, %v instead", n, err)
+	}
+	if _, err := m.To(ctx, 3); !errors.Is(err, ErrInvalidMigration) {
+		t.Errorf("expected %v, got %v instead", ErrInvalidMigration, err)
+	}
+}
+
+func TestMigrator_LocksWhileMigrating(t *testing.T) {
+	f, db := newMigrationFixture(t)
+	m, err := NewMigrator(db, testMigrations(), MigratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.To(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	got := f.d.statements()
+	if got[0] != "SELECT pg_advisory_lock($1)" || got[len(got)-1] != "SELECT pg_advisory_unlock($1)" {
+		t.Errorf("expected migration to hold the advisory lock, got %v instead", got)
+	}
+}
+
+func TestMigrator_FailedMigration(t *testing.T) {
+	f, db := newMigrationFixture(t)
+	fsys := testMigrations()
+	fsys["0002_orders.up.sql"] = &fstest.MapFile{Data: []byte("FAIL")}
+	m, err := NewMigrator(db, fsys, MigratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := m.Up(context.Background())
+	if err == nil || n != 1 {
+		t.Errorf("expected the first migration to apply before the error, got %v, %v instead", n, err)
+	}
+	if len(f.rows) != 1 {
+		t.Errorf("expected 1 recorded migration, got %v instead", len(f.rows))
+	}
+	want := []string{"BEGIN", "FAIL", "ROLLBACK"}
+	got := f.d.statements()
+	if got := got[len(got)-4 : len(got)-1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	f, db := newMigrationFixture(t)
+	m, err := NewMigrator(db, testMigrations(), MigratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	delete(f.rows, 2)
+	f.rows[1][2] = "stale"
+	f.rows[7] = []driver.Value{int64(7), "gone", "x", time.Unix(7, 0)}
+	f.mu.Unlock()
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MigrationStatus{
+		{Version: 1, Name: "users", Applied: true, AppliedAt: time.Unix(1, 0), Modified: true},
+		{Version: 2, Name: "orders", OutOfOrder: true},
+		{Version: 7, Name: "gone", Applied: true, AppliedAt: time.Unix(7, 0), Missing: true},
+		{Version: 10, Name: "index", Applied: true, AppliedAt: time.Unix(10, 0)},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("expected %+v, got %+v instead", want, statuses)
+	}
+
+	_, err = m.Up(ctx)
+	for _, target := range []error{ErrMigrationModified, ErrMigrationMissing, ErrMigrationOutOfOrder} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected %v, got %v instead", target, err)
+		}
+	}
+}
+
+func TestMigrator_StatusWithoutTable(t *testing.T) {
+	f, db := newMigrationFixture(t)
+	m, err := NewMigrator(db, testMigrations(), MigratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MigrationStatus{
+		{Version: 1, Name: "users"},
+		{Version: 2, Name: "orders"},
+		{Version: 10, Name: "index"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("expected %+v, got %+v instead", want, statuses)
+	}
+	if f.table {
+		t.Error("expected Status not to create the migrations table")
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	got := quoteIdentifier(`ops.schema "migrations"`)
+	want := `"ops"."schema ""migrations"""`
+	if got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
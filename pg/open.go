@@ -0,0 +1,97 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DefaultPingTimeout is how long Open waits for the initial ping to succeed
+// when ctx has no earlier deadline.
+const DefaultPingTimeout = 5 * time.Second
+
+// DB is a connection pool opened from a ConnectionConfig. It embeds *sql.DB,
+// so it can be used anywhere the database/sql API is expected.
+type DB struct {
+	*sql.DB
+	driverName string
+	cfg        ConnectionConfig
+	connector  *Connector
+}
+
+// Open opens a connection pool with the database/sql driver registered as
+// driverName, such as "pgx" or "postgres". Connections are made by a
+// Connector, which passes the DSN built by cfg.ODBC for each of the hosts of
+// cfg to the driver, and checks the TargetSessionAttrs of cfg itself. The
+// libpq-only Service and keepalive fields of cfg are not passed to the
+// driver, so cfg should be resolved with Resolve first, and keepalives are
+// left to the driver defaults.
+//
+// cfg is checked with Validate before the pool is opened, and the
+// MaxOpenConns, MaxIdleConns, ConnMaxLifetime and ConnMaxIdleTime pool
+// settings of cfg are applied to it. Open then pings the database, waiting
+// at most DefaultPingTimeout, and closes the pool if the ping fails.
+func Open(ctx context.Context, driverName string, cfg *ConnectionConfig) (*DB, error) {
+	connector, err := NewConnector(driverName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	cfg.apply(db)
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pg: ping: %w", err)
+	}
+	return &DB{
+		DB:         db,
+		driverName: driverName,
+		cfg:        *cfg,
+		connector:  connector,
+	}, nil
+}
+
+// DriverName returns the name of the database/sql driver the pool was opened
+// with.
+func (db *DB) DriverName() string {
+	return db.driverName
+}
+
+// LastHost returns the host the pool most recently opened a connection to.
+func (db *DB) LastHost() (HostPort, bool) {
+	return db.connector.LastHost()
+}
+
+// Config returns a copy of the ConnectionConfig the pool was opened with.
+func (db *DB) Config() ConnectionConfig {
+	return db.cfg
+}
+
+// apply sets the connection pool settings of c on db.
+func (c *ConnectionConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	if c.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(time.Duration(c.ConnMaxLifetime) * time.Second)
+	db.SetConnMaxIdleTime(time.Duration(c.ConnMaxIdleTime) * time.Second)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	d, name := newFakeDriver(t)
+	cfg := &ConnectionConfig{
+		Host:            pg,
+		User:            pg,
+		Password:        pg,
+		Database:        pg,
+		SSLMode:         SSLModeDisable,
+		MaxOpenConns:    7,
+		MaxIdleConns:    3,
+		ConnMaxLifetime: 60,
+		ConnMaxIdleTime: 30,
+	}
+
+	db, err := Open(context.Background(), name, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error from Open: %v", err)
+	}
+	defer db.Close()
+
+	if db.DriverName() != name || db.Config().Host != pg {
+		t.Errorf("expected DB to remember driver %v and config, got %v", name, db.DriverName())
+	}
+	if dsns := d.opened(); len(dsns) != 1 || dsns[0] != cfg.ODBC() {
+		t.Errorf("expected driver to be opened with %v, got %v instead", cfg.ODBC(), dsns)
+	}
+	if h, ok := db.LastHost(); h.Host != pg || !ok {
+		t.Errorf("expected last host %v, got %v instead", pg, h)
+	}
+	if stats := db.Stats(); stats.MaxOpenConnections != 7 {
+		t.Errorf("expected MaxOpenConnections 7, got %v instead", stats.MaxOpenConnections)
+	}
+}
+
+func TestOpen_PingFails(t *testing.T) {
+	d, name := newFakeDriver(t)
+	errRefused := errors.New("connection refused")
+	d.ping = func(string) error {
+		return errRefused
+	}
+
+	db, err := Open(context.Background(), name, &ConnectionConfig{Host: pg})
+	if db != nil || !errors.Is(err, errRefused) {
+		t.Errorf("expected ping error %v, got %v instead", errRefused, err)
+	}
+}
+
+func TestOpen_UnknownDriver(t *testing.T) {
+	if _, err := Open(context.Background(), "pg-driver-that-does-not-exist", &ConnectionConfig{}); err == nil {
+		t.Error("expected error opening unknown driver")
+	}
+}
@@ -0,0 +1,679 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgerr
+
+// The SQLSTATE codes of PostgreSQL 16, grouped by class.
+// See more at https://www.postgresql.org/docs/16/errcodes-appendix.html.
+const (
+	// Class 00 - Successful Completion
+	SuccessfulCompletion = "00000"
+
+	// Class 01 - Warning
+	Warning                          = "01000"
+	DynamicResultSetsReturned        = "0100C"
+	ImplicitZeroBitPadding           = "01008"
+	NullValueEliminatedInSetFunction = "01003"
+	PrivilegeNotGranted              = "01007"
+	PrivilegeNotRevoked              = "01006"
+	StringDataRightTruncationWarning = "01004"
+	DeprecatedFeature                = "01P01"
+
+	// Class 02 - No Data
+	NoData                                = "02000"
+	NoAdditionalDynamicResultSetsReturned = "02001"
+
+	// Class 03 - SQL Statement Not Yet Complete
+	SQLStatementNotYetComplete = "03000"
+
+	// Class 08 - Connection Exception
+	ConnectionException                           = "08000"
+	ConnectionDoesNotExist                        = "08003"
+	ConnectionFailure                             = "08006"
+	SQLClientUnableToEstablishSQLConnection       = "08001"
+	SQLServerRejectedEstablishmentOfSQLConnection = "08004"
+	TransactionResolutionUnknown                  = "08007"
+	ProtocolViolation                             = "08P01"
+
+	// Class 09 - Triggered Action Exception
+	TriggeredActionException = "09000"
+
+	// Class 0A - Feature Not Supported
+	FeatureNotSupported = "0A000"
+
+	// Class 0B - Invalid Transaction Initiation
+	InvalidTransactionInitiation = "0B000"
+
+	// Class 0F - Locator Exception
+	LocatorException            = "0F000"
+	InvalidLocatorSpecification = "0F001"
+
+	// Class 0L - Invalid Grantor
+	InvalidGrantor        = "0L000"
+	InvalidGrantOperation = "0LP01"
+
+	// Class 0P - Invalid Role Specification
+	InvalidRoleSpecification = "0P000"
+
+	// Class 0Z - Diagnostics Exception
+	DiagnosticsException                           = "0Z000"
+	StackedDiagnosticsAccessedWithoutActiveHandler = "0Z002"
+
+	// Class 20 - Case Not Found
+	CaseNotFound = "20000"
+
+	// Class 21 - Cardinality Violation
+	CardinalityViolation = "21000"
+
+	// Class 22 - Data Exception
+	DataException                             = "22000"
+	ArraySubscriptError                       = "2202E"
+	CharacterNotInRepertoire                  = "22021"
+	DatetimeFieldOverflow                     = "22008"
+	DivisionByZero                            = "22012"
+	ErrorInAssignment                         = "22005"
+	EscapeCharacterConflict                   = "2200B"
+	IndicatorOverflow                         = "22022"
+	IntervalFieldOverflow                     = "22015"
+	InvalidArgumentForLogarithm               = "2201E"
+	InvalidArgumentForNtileFunction           = "22014"
+	InvalidArgumentForNthValueFunction        = "22016"
+	InvalidArgumentForPowerFunction           = "2201F"
+	InvalidArgumentForWidthBucketFunction     = "2201G"
+	InvalidCharacterValueForCast              = "22018"
+	InvalidDatetimeFormat                     = "22007"
+	InvalidEscapeCharacter                    = "22019"
+	InvalidEscapeOctet                        = "2200D"
+	InvalidEscapeSequence                     = "22025"
+	NonstandardUseOfEscapeCharacter           = "22P06"
+	InvalidIndicatorParameterValue            = "22010"
+	InvalidParameterValue                     = "22023"
+	InvalidPrecedingOrFollowingSize           = "22013"
+	InvalidRegularExpression                  = "2201B"
+	InvalidRowCountInLimitClause              = "2201W"
+	InvalidRowCountInResultOffsetClause       = "2201X"
+	InvalidTablesampleArgument                = "2202H"
+	InvalidTablesampleRepeat                  = "2202G"
+	InvalidTimeZoneDisplacementValue          = "22009"
+	InvalidUseOfEscapeCharacter               = "2200C"
+	MostSpecificTypeMismatch                  = "2200G"
+	NullValueNotAllowedDataException          = "22004"
+	NullValueNoIndicatorParameter             = "22002"
+	NumericValueOutOfRange                    = "22003"
+	SequenceGeneratorLimitExceeded            = "2200H"
+	StringDataLengthMismatch                  = "22026"
+	StringDataRightTruncationDataException    = "22001"
+	SubstringError                            = "22011"
+	TrimError                                 = "22027"
+	UnterminatedCString                       = "22024"
+	ZeroLengthCharacterString                 = "2200F"
+	FloatingPointException                    = "22P01"
+	InvalidTextRepresentation                 = "22P02"
+	InvalidBinaryRepresentation               = "22P03"
+	BadCopyFileFormat                         = "22P04"
+	UntranslatableCharacter                   = "22P05"
+	NotAnXMLDocument                          = "2200L"
+	InvalidXMLDocument                        = "2200M"
+	InvalidXMLContent                         = "2200N"
+	InvalidXMLComment                         = "2200S"
+	InvalidXMLProcessingInstruction           = "2200T"
+	DuplicateJSONObjectKeyValue               = "22030"
+	InvalidArgumentForSQLJSONDatetimeFunction = "22031"
+	InvalidJSONText                           = "22032"
+	InvalidSQLJSONSubscript                   = "22033"
+	MoreThanOneSQLJSONItem                    = "22034"
+	NoSQLJSONItem                             = "22035"
+	NonNumericSQLJSONItem                     = "22036"
+	NonUniqueKeysInAJSONObject                = "22037"
+	SingletonSQLJSONItemRequired              = "22038"
+	SQLJSONArrayNotFound                      = "22039"
+	SQLJSONMemberNotFound                     = "2203A"
+	SQLJSONNumberNotFound                     = "2203B"
+	SQLJSONObjectNotFound                     = "2203C"
+	TooManyJSONArrayElements                  = "2203D"
+	TooManyJSONObjectMembers                  = "2203E"
+	SQLJSONScalarRequired                     = "2203F"
+	SQLJSONItemCannotBeCastToTargetType       = "2203G"
+
+	// Class 23 - Integrity Constraint Violation
+	IntegrityConstraintViolation = "23000"
+	RestrictViolation            = "23001"
+	NotNullViolation             = "23502"
+	ForeignKeyViolation          = "23503"
+	UniqueViolation              = "23505"
+	CheckViolation               = "23514"
+	ExclusionViolation           = "23P01"
+
+	// Class 24 - Invalid Cursor State
+	InvalidCursorState = "24000"
+
+	// Class 25 - Invalid Transaction State
+	InvalidTransactionState                         = "25000"
+	ActiveSQLTransaction                            = "25001"
+	BranchTransactionAlreadyActive                  = "25002"
+	HeldCursorRequiresSameIsolationLevel            = "25008"
+	InappropriateAccessModeForBranchTransaction     = "25003"
+	InappropriateIsolationLevelForBranchTransaction = "25004"
+	NoActiveSQLTransactionForBranchTransaction      = "25005"
+	ReadOnlySQLTransaction                          = "25006"
+	SchemaAndDataStatementMixingNotSupported        = "25007"
+	NoActiveSQLTransaction                          = "25P01"
+	InFailedSQLTransaction                          = "25P02"
+	IdleInTransactionSessionTimeout                 = "25P03"
+
+	// Class 26 - Invalid SQL Statement Name
+	InvalidSQLStatementName = "26000"
+
+	// Class 27 - Triggered Data Change Violation
+	TriggeredDataChangeViolation = "27000"
+
+	// Class 28 - Invalid Authorization Specification
+	InvalidAuthorizationSpecification = "28000"
+	InvalidPassword                   = "28P01"
+
+	// Class 2B - Dependent Privilege Descriptors Still Exist
+	DependentPrivilegeDescriptorsStillExist = "2B000"
+	DependentObjectsStillExist              = "2BP01"
+
+	// Class 2D - Invalid Transaction Termination
+	InvalidTransactionTermination = "2D000"
+
+	// Class 2F - SQL Routine Exception
+	SQLRoutineException                                = "2F000"
+	FunctionExecutedNoReturnStatement                  = "2F005"
+	ModifyingSQLDataNotPermittedSQLRoutineException    = "2F002"
+	ProhibitedSQLStatementAttemptedSQLRoutineException = "2F003"
+	ReadingSQLDataNotPermittedSQLRoutineException      = "2F004"
+
+	// Class 34 - Invalid Cursor Name
+	InvalidCursorName = "34000"
+
+	// Class 38 - External Routine Exception
+	ExternalRoutineException                                = "38000"
+	ContainingSQLNotPermitted                               = "38001"
+	ModifyingSQLDataNotPermittedExternalRoutineException    = "38002"
+	ProhibitedSQLStatementAttemptedExternalRoutineException = "38003"
+	ReadingSQLDataNotPermittedExternalRoutineException      = "38004"
+
+	// Class 39 - External Routine Invocation Exception
+	ExternalRoutineInvocationException                    = "39000"
+	InvalidSQLstateReturned                               = "39001"
+	NullValueNotAllowedExternalRoutineInvocationException = "39004"
+	TriggerProtocolViolated                               = "39P01"
+	SRFProtocolViolated                                   = "39P02"
+	EventTriggerProtocolViolated                          = "39P03"
+
+	// Class 3B - Savepoint Exception
+	SavepointException            = "3B000"
+	InvalidSavepointSpecification = "3B001"
+
+	// Class 3D - Invalid Catalog Name
+	InvalidCatalogName = "3D000"
+
+	// Class 3F - Invalid Schema Name
+	InvalidSchemaName = "3F000"
+
+	// Class 40 - Transaction Rollback
+	TransactionRollback                     = "40000"
+	TransactionIntegrityConstraintViolation = "40002"
+	SerializationFailure                    = "40001"
+	StatementCompletionUnknown              = "40003"
+	DeadlockDetected                        = "40P01"
+
+	// Class 42 - Syntax Error or Access Rule Violation
+	SyntaxErrorOrAccessRuleViolation   = "42000"
+	SyntaxError                        = "42601"
+	InsufficientPrivilege              = "42501"
+	CannotCoerce                       = "42846"
+	GroupingError                      = "42803"
+	WindowingError                     = "42P20"
+	InvalidRecursion                   = "42P19"
+	InvalidForeignKey                  = "42830"
+	InvalidName                        = "42602"
+	NameTooLong                        = "42622"
+	ReservedName                       = "42939"
+	DatatypeMismatch                   = "42804"
+	IndeterminateDatatype              = "42P18"
+	CollationMismatch                  = "42P21"
+	IndeterminateCollation             = "42P22"
+	WrongObjectType                    = "42809"
+	GeneratedAlways                    = "428C9"
+	UndefinedColumn                    = "42703"
+	UndefinedFunction                  = "42883"
+	UndefinedTable                     = "42P01"
+	UndefinedParameter                 = "42P02"
+	UndefinedObject                    = "42704"
+	DuplicateColumn                    = "42701"
+	DuplicateCursor                    = "42P03"
+	DuplicateDatabase                  = "42P04"
+	DuplicateFunction                  = "42723"
+	DuplicatePreparedStatement         = "42P05"
+	DuplicateSchema                    = "42P06"
+	DuplicateTable                     = "42P07"
+	DuplicateAlias                     = "42712"
+	DuplicateObject                    = "42710"
+	AmbiguousColumn                    = "42702"
+	AmbiguousFunction                  = "42725"
+	AmbiguousParameter                 = "42P08"
+	AmbiguousAlias                     = "42P09"
+	InvalidColumnReference             = "42P10"
+	InvalidColumnDefinition            = "42611"
+	InvalidCursorDefinition            = "42P11"
+	InvalidDatabaseDefinition          = "42P12"
+	InvalidFunctionDefinition          = "42P13"
+	InvalidPreparedStatementDefinition = "42P14"
+	InvalidSchemaDefinition            = "42P15"
+	InvalidTableDefinition             = "42P16"
+	InvalidObjectDefinition            = "42P17"
+
+	// Class 44 - WITH CHECK OPTION Violation
+	WithCheckOptionViolation = "44000"
+
+	// Class 53 - Insufficient Resources
+	InsufficientResources      = "53000"
+	DiskFull                   = "53100"
+	OutOfMemory                = "53200"
+	TooManyConnections         = "53300"
+	ConfigurationLimitExceeded = "53400"
+
+	// Class 54 - Program Limit Exceeded
+	ProgramLimitExceeded = "54000"
+	StatementTooComplex  = "54001"
+	TooManyColumns       = "54011"
+	TooManyArguments     = "54023"
+
+	// Class 55 - Object Not In Prerequisite State
+	ObjectNotInPrerequisiteState = "55000"
+	ObjectInUse                  = "55006"
+	CantChangeRuntimeParam       = "55P02"
+	LockNotAvailable             = "55P03"
+	UnsafeNewEnumValueUsage      = "55P04"
+
+	// Class 57 - Operator Intervention
+	OperatorIntervention = "57000"
+	QueryCanceled        = "57014"
+	AdminShutdown        = "57P01"
+	CrashShutdown        = "57P02"
+	CannotConnectNow     = "57P03"
+	DatabaseDropped      = "57P04"
+	IdleSessionTimeout   = "57P05"
+
+	// Class 58 - System Error
+	SystemError   = "58000"
+	IOError       = "58030"
+	UndefinedFile = "58P01"
+	DuplicateFile = "58P02"
+
+	// Class 72 - Snapshot Failure
+	SnapshotTooOld = "72000"
+
+	// Class F0 - Configuration File Error
+	ConfigFileError = "F0000"
+	LockFileExists  = "F0001"
+
+	// Class HV - Foreign Data Wrapper Error
+	FDWError                             = "HV000"
+	FDWColumnNameNotFound                = "HV005"
+	FDWDynamicParameterValueNeeded       = "HV002"
+	FDWFunctionSequenceError             = "HV010"
+	FDWInconsistentDescriptorInformation = "HV021"
+	FDWInvalidAttributeValue             = "HV024"
+	FDWInvalidColumnName                 = "HV007"
+	FDWInvalidColumnNumber               = "HV008"
+	FDWInvalidDataType                   = "HV004"
+	FDWInvalidDataTypeDescriptors        = "HV006"
+	FDWInvalidDescriptorFieldIdentifier  = "HV091"
+	FDWInvalidHandle                     = "HV00B"
+	FDWInvalidOptionIndex                = "HV00C"
+	FDWInvalidOptionName                 = "HV00D"
+	FDWInvalidStringLengthOrBufferLength = "HV090"
+	FDWInvalidStringFormat               = "HV00A"
+	FDWInvalidUseOfNullPointer           = "HV009"
+	FDWTooManyHandles                    = "HV014"
+	FDWOutOfMemory                       = "HV001"
+	FDWNoSchemas                         = "HV00P"
+	FDWOptionNameNotFound                = "HV00J"
+	FDWReplyHandle                       = "HV00K"
+	FDWSchemaNotFound                    = "HV00Q"
+	FDWTableNotFound                     = "HV00R"
+	FDWUnableToCreateExecution           = "HV00L"
+	FDWUnableToCreateReply               = "HV00M"
+	FDWUnableToEstablishConnection       = "HV00N"
+
+	// Class P0 - PL/pgSQL Error
+	PLpgSQLError   = "P0000"
+	RaiseException = "P0001"
+	NoDataFound    = "P0002"
+	TooManyRows    = "P0003"
+	AssertFailure  = "P0004"
+
+	// Class XX - Internal Error
+	InternalError  = "XX000"
+	DataCorrupted  = "XX001"
+	IndexCorrupted = "XX002"
+)
+
+// classes maps the first two characters of every SQLSTATE code to the name
+// of its class.
+var classes = map[string]string{
+	"00": "Successful Completion",
+	"01": "Warning",
+	"02": "No Data",
+	"03": "SQL Statement Not Yet Complete",
+	"08": "Connection Exception",
+	"09": "Triggered Action Exception",
+	"0A": "Feature Not Supported",
+	"0B": "Invalid Transaction Initiation",
+	"0F": "Locator Exception",
+	"0L": "Invalid Grantor",
+	"0P": "Invalid Role Specification",
+	"0Z": "Diagnostics Exception",
+	"20": "Case Not Found",
+	"21": "Cardinality Violation",
+	"22": "Data Exception",
+	"23": "Integrity Constraint Violation",
+	"24": "Invalid Cursor State",
+	"25": "Invalid Transaction State",
+	"26": "Invalid SQL Statement Name",
+	"27": "Triggered Data Change Violation",
+	"28": "Invalid Authorization Specification",
+	"2B": "Dependent Privilege Descriptors Still Exist",
+	"2D": "Invalid Transaction Termination",
+	"2F": "SQL Routine Exception",
+	"34": "Invalid Cursor Name",
+	"38": "External Routine Exception",
+	"39": "External Routine Invocation Exception",
+	"3B": "Savepoint Exception",
+	"3D": "Invalid Catalog Name",
+	"3F": "Invalid Schema Name",
+	"40": "Transaction Rollback",
+	"42": "Syntax Error or Access Rule Violation",
+	"44": "WITH CHECK OPTION Violation",
+	"53": "Insufficient Resources",
+	"54": "Program Limit Exceeded",
+	"55": "Object Not In Prerequisite State",
+	"57": "Operator Intervention",
+	"58": "System Error",
+	"72": "Snapshot Failure",
+	"F0": "Configuration File Error",
+	"HV": "Foreign Data Wrapper Error",
+	"P0": "PL/pgSQL Error",
+	"XX": "Internal Error",
+}
+
+// conditions maps every SQLSTATE code to its condition name.
+var conditions = map[string]string{
+	SuccessfulCompletion:                                    "successful_completion",
+	Warning:                                                 "warning",
+	DynamicResultSetsReturned:                               "dynamic_result_sets_returned",
+	ImplicitZeroBitPadding:                                  "implicit_zero_bit_padding",
+	NullValueEliminatedInSetFunction:                        "null_value_eliminated_in_set_function",
+	PrivilegeNotGranted:                                     "privilege_not_granted",
+	PrivilegeNotRevoked:                                     "privilege_not_revoked",
+	StringDataRightTruncationWarning:                        "string_data_right_truncation",
+	DeprecatedFeature:                                       "deprecated_feature",
+	NoData:                                                  "no_data",
+	NoAdditionalDynamicResultSetsReturned:                   "no_additional_dynamic_result_sets_returned",
+	SQLStatementNotYetComplete:                              "sql_statement_not_yet_complete",
+	ConnectionException:                                     "connection_exception",
+	ConnectionDoesNotExist:                                  "connection_does_not_exist",
+	ConnectionFailure:                                       "connection_failure",
+	SQLClientUnableToEstablishSQLConnection:                 "sqlclient_unable_to_establish_sqlconnection",
+	SQLServerRejectedEstablishmentOfSQLConnection:           "sqlserver_rejected_establishment_of_sqlconnection",
+	TransactionResolutionUnknown:                            "transaction_resolution_unknown",
+	ProtocolViolation:                                       "protocol_violation",
+	TriggeredActionException:                                "triggered_action_exception",
+	FeatureNotSupported:                                     "feature_not_supported",
+	InvalidTransactionInitiation:                            "invalid_transaction_initiation",
+	LocatorException:                                        "locator_exception",
+	InvalidLocatorSpecification:                             "invalid_locator_specification",
+	InvalidGrantor:                                          "invalid_grantor",
+	InvalidGrantOperation:                                   "invalid_grant_operation",
+	InvalidRoleSpecification:                                "invalid_role_specification",
+	DiagnosticsException:                                    "diagnostics_exception",
+	StackedDiagnosticsAccessedWithoutActiveHandler:          "stacked_diagnostics_accessed_without_active_handler",
+	CaseNotFound:                                            "case_not_found",
+	CardinalityViolation:                                    "cardinality_violation",
+	DataException:                                           "data_exception",
+	ArraySubscriptError:                                     "array_subscript_error",
+	CharacterNotInRepertoire:                                "character_not_in_repertoire",
+	DatetimeFieldOverflow:                                   "datetime_field_overflow",
+	DivisionByZero:                                          "division_by_zero",
+	ErrorInAssignment:                                       "error_in_assignment",
+	EscapeCharacterConflict:                                 "escape_character_conflict",
+	IndicatorOverflow:                                       "indicator_overflow",
+	IntervalFieldOverflow:                                   "interval_field_overflow",
+	InvalidArgumentForLogarithm:                             "invalid_argument_for_logarithm",
+	InvalidArgumentForNtileFunction:                         "invalid_argument_for_ntile_function",
+	InvalidArgumentForNthValueFunction:                      "invalid_argument_for_nth_value_function",
+	InvalidArgumentForPowerFunction:                         "invalid_argument_for_power_function",
+	InvalidArgumentForWidthBucketFunction:                   "invalid_argument_for_width_bucket_function",
+	InvalidCharacterValueForCast:                            "invalid_character_value_for_cast",
+	InvalidDatetimeFormat:                                   "invalid_datetime_format",
+	InvalidEscapeCharacter:                                  "invalid_escape_character",
+	InvalidEscapeOctet:                                      "invalid_escape_octet",
+	InvalidEscapeSequence:                                   "invalid_escape_sequence",
+	NonstandardUseOfEscapeCharacter:                         "nonstandard_use_of_escape_character",
+	InvalidIndicatorParameterValue:                          "invalid_indicator_parameter_value",
+	InvalidParameterValue:                                   "invalid_parameter_value",
+	InvalidPrecedingOrFollowingSize:                         "invalid_preceding_or_following_size",
+	InvalidRegularExpression:                                "invalid_regular_expression",
+	InvalidRowCountInLimitClause:                            "invalid_row_count_in_limit_clause",
+	InvalidRowCountInResultOffsetClause:                     "invalid_row_count_in_result_offset_clause",
+	InvalidTablesampleArgument:                              "invalid_tablesample_argument",
+	InvalidTablesampleRepeat:                                "invalid_tablesample_repeat",
+	InvalidTimeZoneDisplacementValue:                        "invalid_time_zone_displacement_value",
+	InvalidUseOfEscapeCharacter:                             "invalid_use_of_escape_character",
+	MostSpecificTypeMismatch:                                "most_specific_type_mismatch",
+	NullValueNotAllowedDataException:                        "null_value_not_allowed",
+	NullValueNoIndicatorParameter:                           "null_value_no_indicator_parameter",
+	NumericValueOutOfRange:                                  "numeric_value_out_of_range",
+	SequenceGeneratorLimitExceeded:                          "sequence_generator_limit_exceeded",
+	StringDataLengthMismatch:                                "string_data_length_mismatch",
+	StringDataRightTruncationDataException:                  "string_data_right_truncation",
+	SubstringError:                                          "substring_error",
+	TrimError:                                               "trim_error",
+	UnterminatedCString:                                     "unterminated_c_string",
+	ZeroLengthCharacterString:                               "zero_length_character_string",
+	FloatingPointException:                                  "floating_point_exception",
+	InvalidTextRepresentation:                               "invalid_text_representation",
+	InvalidBinaryRepresentation:                             "invalid_binary_representation",
+	BadCopyFileFormat:                                       "bad_copy_file_format",
+	UntranslatableCharacter:                                 "untranslatable_character",
+	NotAnXMLDocument:                                        "not_an_xml_document",
+	InvalidXMLDocument:                                      "invalid_xml_document",
+	InvalidXMLContent:                                       "invalid_xml_content",
+	InvalidXMLComment:                                       "invalid_xml_comment",
+	InvalidXMLProcessingInstruction:                         "invalid_xml_processing_instruction",
+	DuplicateJSONObjectKeyValue:                             "duplicate_json_object_key_value",
+	InvalidArgumentForSQLJSONDatetimeFunction:               "invalid_argument_for_sql_json_datetime_function",
+	InvalidJSONText:                                         "invalid_json_text",
+	InvalidSQLJSONSubscript:                                 "invalid_sql_json_subscript",
+	MoreThanOneSQLJSONItem:                                  "more_than_one_sql_json_item",
+	NoSQLJSONItem:                                           "no_sql_json_item",
+	NonNumericSQLJSONItem:                                   "non_numeric_sql_json_item",
+	NonUniqueKeysInAJSONObject:                              "non_unique_keys_in_a_json_object",
+	SingletonSQLJSONItemRequired:                            "singleton_sql_json_item_required",
+	SQLJSONArrayNotFound:                                    "sql_json_array_not_found",
+	SQLJSONMemberNotFound:                                   "sql_json_member_not_found",
+	SQLJSONNumberNotFound:                                   "sql_json_number_not_found",
+	SQLJSONObjectNotFound:                                   "sql_json_object_not_found",
+	TooManyJSONArrayElements:                                "too_many_json_array_elements",
+	TooManyJSONObjectMembers:                                "too_many_json_object_members",
+	SQLJSONScalarRequired:                                   "sql_json_scalar_required",
+	SQLJSONItemCannotBeCastToTargetType:                     "sql_json_item_cannot_be_cast_to_target_type",
+	IntegrityConstraintViolation:                            "integrity_constraint_violation",
+	RestrictViolation:                                       "restrict_violation",
+	NotNullViolation:                                        "not_null_violation",
+	ForeignKeyViolation:                                     "foreign_key_violation",
+	UniqueViolation:                                         "unique_violation",
+	CheckViolation:                                          "check_violation",
+	ExclusionViolation:                                      "exclusion_violation",
+	InvalidCursorState:                                      "invalid_cursor_state",
+	InvalidTransactionState:                                 "invalid_transaction_state",
+	ActiveSQLTransaction:                                    "active_sql_transaction",
+	BranchTransactionAlreadyActive:                          "branch_transaction_already_active",
+	HeldCursorRequiresSameIsolationLevel:                    "held_cursor_requires_same_isolation_level",
+	InappropriateAccessModeForBranchTransaction:             "inappropriate_access_mode_for_branch_transaction",
+	InappropriateIsolationLevelForBranchTransaction:         "inappropriate_isolation_level_for_branch_transaction",
+	NoActiveSQLTransactionForBranchTransaction:              "no_active_sql_transaction_for_branch_transaction",
+	ReadOnlySQLTransaction:                                  "read_only_sql_transaction",
+	SchemaAndDataStatementMixingNotSupported:                "schema_and_data_statement_mixing_not_supported",
+	NoActiveSQLTransaction:                                  "no_active_sql_transaction",
+	InFailedSQLTransaction:                                  "in_failed_sql_transaction",
+	IdleInTransactionSessionTimeout:                         "idle_in_transaction_session_timeout",
+	InvalidSQLStatementName:                                 "invalid_sql_statement_name",
+	TriggeredDataChangeViolation:                            "triggered_data_change_violation",
+	InvalidAuthorizationSpecification:                       "invalid_authorization_specification",
+	InvalidPassword:                                         "invalid_password",
+	DependentPrivilegeDescriptorsStillExist:                 "dependent_privilege_descriptors_still_exist",
+	DependentObjectsStillExist:                              "dependent_objects_still_exist",
+	InvalidTransactionTermination:                           "invalid_transaction_termination",
+	SQLRoutineException:                                     "sql_routine_exception",
+	FunctionExecutedNoReturnStatement:                       "function_executed_no_return_statement",
+	ModifyingSQLDataNotPermittedSQLRoutineException:         "modifying_sql_data_not_permitted",
+	ProhibitedSQLStatementAttemptedSQLRoutineException:      "prohibited_sql_statement_attempted",
+	ReadingSQLDataNotPermittedSQLRoutineException:           "reading_sql_data_not_permitted",
+	InvalidCursorName:                                       "invalid_cursor_name",
+	ExternalRoutineException:                                "external_routine_exception",
+	ContainingSQLNotPermitted:                               "containing_sql_not_permitted",
+	ModifyingSQLDataNotPermittedExternalRoutineException:    "modifying_sql_data_not_permitted",
+	ProhibitedSQLStatementAttemptedExternalRoutineException: "prohibited_sql_statement_attempted",
+	ReadingSQLDataNotPermittedExternalRoutineException:      "reading_sql_data_not_permitted",
+	ExternalRoutineInvocationException:                      "external_routine_invocation_exception",
+	InvalidSQLstateReturned:                                 "invalid_sqlstate_returned",
+	NullValueNotAllowedExternalRoutineInvocationException:   "null_value_not_allowed",
+	TriggerProtocolViolated:                                 "trigger_protocol_violated",
+	SRFProtocolViolated:                                     "srf_protocol_violated",
+	EventTriggerProtocolViolated:                            "event_trigger_protocol_violated",
+	SavepointException:                                      "savepoint_exception",
+	InvalidSavepointSpecification:                           "invalid_savepoint_specification",
+	InvalidCatalogName:                                      "invalid_catalog_name",
+	InvalidSchemaName:                                       "invalid_schema_name",
+	TransactionRollback:                                     "transaction_rollback",
+	TransactionIntegrityConstraintViolation:                 "transaction_integrity_constraint_violation",
+	SerializationFailure:                                    "serialization_failure",
+	StatementCompletionUnknown:                              "statement_completion_unknown",
+	DeadlockDetected:                                        "deadlock_detected",
+	SyntaxErrorOrAccessRuleViolation:                        "syntax_error_or_access_rule_violation",
+	SyntaxError:                                             "syntax_error",
+	InsufficientPrivilege:                                   "insufficient_privilege",
+	CannotCoerce:                                            "cannot_coerce",
+	GroupingError:                                           "grouping_error",
+	WindowingError:                                          "windowing_error",
+	InvalidRecursion:                                        "invalid_recursion",
+	InvalidForeignKey:                                       "invalid_foreign_key",
+	InvalidName:                                             "invalid_name",
+	NameTooLong:                                             "name_too_long",
+	ReservedName:                                            "reserved_name",
+	DatatypeMismatch:                                        "datatype_mismatch",
+	IndeterminateDatatype:                                   "indeterminate_datatype",
+	CollationMismatch:                                       "collation_mismatch",
+	IndeterminateCollation:                                  "indeterminate_collation",
+	WrongObjectType:                                         "wrong_object_type",
+	GeneratedAlways:                                         "generated_always",
+	UndefinedColumn:                                         "undefined_column",
+	UndefinedFunction:                                       "undefined_function",
+	UndefinedTable:                                          "undefined_table",
+	UndefinedParameter:                                      "undefined_parameter",
+	UndefinedObject:                                         "undefined_object",
+	DuplicateColumn:                                         "duplicate_column",
+	DuplicateCursor:                                         "duplicate_cursor",
+	DuplicateDatabase:                                       "duplicate_database",
+	DuplicateFunction:                                       "duplicate_function",
+	DuplicatePreparedStatement:                              "duplicate_prepared_statement",
+	DuplicateSchema:                                         "duplicate_schema",
+	DuplicateTable:                                          "duplicate_table",
+	DuplicateAlias:                                          "duplicate_alias",
+	DuplicateObject:                                         "duplicate_object",
+	AmbiguousColumn:                                         "ambiguous_column",
+	AmbiguousFunction:                                       "ambiguous_function",
+	AmbiguousParameter:                                      "ambiguous_parameter",
+	AmbiguousAlias:                                          "ambiguous_alias",
+	InvalidColumnReference:                                  "invalid_column_reference",
+	InvalidColumnDefinition:                                 "invalid_column_definition",
+	InvalidCursorDefinition:                                 "invalid_cursor_definition",
+	InvalidDatabaseDefinition:                               "invalid_database_definition",
+	InvalidFunctionDefinition:                               "invalid_function_definition",
+	InvalidPreparedStatementDefinition:                      "invalid_prepared_statement_definition",
+	InvalidSchemaDefinition:                                 "invalid_schema_definition",
+	InvalidTableDefinition:                                  "invalid_table_definition",
+	InvalidObjectDefinition:                                 "invalid_object_definition",
+	WithCheckOptionViolation:                                "with_check_option_violation",
+	InsufficientResources:                                   "insufficient_resources",
+	DiskFull:                                                "disk_full",
+	OutOfMemory:                                             "out_of_memory",
+	TooManyConnections:                                      "too_many_connections",
+	ConfigurationLimitExceeded:                              "configuration_limit_exceeded",
+	ProgramLimitExceeded:                                    "program_limit_exceeded",
+	StatementTooComplex:                                     "statement_too_complex",
+	TooManyColumns:                                          "too_many_columns",
+	TooManyArguments:                                        "too_many_arguments",
+	ObjectNotInPrerequisiteState:                            "object_not_in_prerequisite_state",
+	ObjectInUse:                                             "object_in_use",
+	CantChangeRuntimeParam:                                  "cant_change_runtime_param",
+	LockNotAvailable:                                        "lock_not_available",
+	UnsafeNewEnumValueUsage:                                 "unsafe_new_enum_value_usage",
+	OperatorIntervention:                                    "operator_intervention",
+	QueryCanceled:                                           "query_canceled",
+	AdminShutdown:                                           "admin_shutdown",
+	CrashShutdown:                                           "crash_shutdown",
+	CannotConnectNow:                                        "cannot_connect_now",
+	DatabaseDropped:                                         "database_dropped",
+	IdleSessionTimeout:                                      "idle_session_timeout",
+	SystemError:                                             "system_error",
+	IOError:                                                 "io_error",
+	UndefinedFile:                                           "undefined_file",
+	DuplicateFile:                                           "duplicate_file",
+	SnapshotTooOld:                                          "snapshot_too_old",
+	ConfigFileError:                                         "config_file_error",
+	LockFileExists:                                          "lock_file_exists",
+	FDWError:                                                "fdw_error",
+	FDWColumnNameNotFound:                                   "fdw_column_name_not_found",
+	FDWDynamicParameterValueNeeded:                          "fdw_dynamic_parameter_value_needed",
+	FDWFunctionSequenceError:                                "fdw_function_sequence_error",
+	FDWInconsistentDescriptorInformation:                    "fdw_inconsistent_descriptor_information",
+	FDWInvalidAttributeValue:                                "fdw_invalid_attribute_value",
+	FDWInvalidColumnName:                                    "fdw_invalid_column_name",
+	FDWInvalidColumnNumber:                                  "fdw_invalid_column_number",
+	FDWInvalidDataType:                                      "fdw_invalid_data_type",
+	FDWInvalidDataTypeDescriptors:                           "fdw_invalid_data_type_descriptors",
+	FDWInvalidDescriptorFieldIdentifier:                     "fdw_invalid_descriptor_field_identifier",
+	FDWInvalidHandle:                                        "fdw_invalid_handle",
+	FDWInvalidOptionIndex:                                   "fdw_invalid_option_index",
+	FDWInvalidOptionName:                                    "fdw_invalid_option_name",
+	FDWInvalidStringLengthOrBufferLength:                    "fdw_invalid_string_length_or_buffer_length",
+	FDWInvalidStringFormat:                                  "fdw_invalid_string_format",
+	FDWInvalidUseOfNullPointer:                              "fdw_invalid_use_of_null_pointer",
+	FDWTooManyHandles:                                       "fdw_too_many_handles",
+	FDWOutOfMemory:                                          "fdw_out_of_memory",
+	FDWNoSchemas:                                            "fdw_no_schemas",
+	FDWOptionNameNotFound:                                   "fdw_option_name_not_found",
+	FDWReplyHandle:                                          "fdw_reply_handle",
+	FDWSchemaNotFound:                                       "fdw_schema_not_found",
+	FDWTableNotFound:                                        "fdw_table_not_found",
+	FDWUnableToCreateExecution:                              "fdw_unable_to_create_execution",
+	FDWUnableToCreateReply:                                  "fdw_unable_to_create_reply",
+	FDWUnableToEstablishConnection:                          "fdw_unable_to_establish_connection",
+	PLpgSQLError:                                            "plpgsql_error",
+	RaiseException:                                          "raise_exception",
+	NoDataFound:                                             "no_data_found",
+	TooManyRows:                                             "too_many_rows",
+	AssertFailure:                                           "assert_failure",
+	InternalError:                                           "internal_error",
+	DataCorrupted:                                           "data_corrupted",
+	IndexCorrupted:                                          "index_corrupted",
+}
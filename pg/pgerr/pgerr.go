@@ -0,0 +1,192 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pgerr classifies Postgres errors by their SQLSTATE code, so that
+// callers can branch on unique violations, serialization failures or lost
+// connections without matching error messages. The code is extracted from
+// the errors of any driver, such as pgx and lib/pq.
+package pgerr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+)
+
+// SQLState returns the SQLSTATE code of the first error in the chain of err
+// that carries one. An error carries a code if it has a SQLState() string
+// method, like the errors of pgx, or a five character string Code field,
+// like the errors of lib/pq. If no error carries a code, it will return an
+// empty string, and false.
+func SQLState(err error) (string, bool) {
+	var code string
+	walk(err, func(err error) bool {
+		code = sqlState(err)
+		return code != ""
+	})
+	return code, code != ""
+}
+
+// Class returns the name of the class of code, such as "Integrity Constraint
+// Violation" for 23505, or an empty string if the class is unknown.
+func Class(code string) string {
+	if len(code) != 5 {
+		return ""
+	}
+	return classes[code[:2]]
+}
+
+// Condition returns the condition name of code, such as "unique_violation"
+// for 23505, or an empty string if the code is unknown.
+func Condition(code string) string {
+	return conditions[code]
+}
+
+// Is reports whether the SQLSTATE code of err is code.
+func Is(err error, code string) bool {
+	c, ok := SQLState(err)
+	return ok && c == code
+}
+
+// IsClass reports whether the SQLSTATE code of err is in class, given as the
+// two character class code such as "23".
+func IsClass(err error, class string) bool {
+	c, ok := SQLState(err)
+	return ok && len(c) == 5 && len(class) == 2 && c[:2] == class
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	return Is(err, UniqueViolation)
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key constraint
+// violation.
+func IsForeignKeyViolation(err error) bool {
+	return Is(err, ForeignKeyViolation)
+}
+
+// IsNotNullViolation reports whether err is a not null constraint violation.
+func IsNotNullViolation(err error) bool {
+	return Is(err, NotNullViolation)
+}
+
+// IsCheckViolation reports whether err is a check constraint violation.
+func IsCheckViolation(err error) bool {
+	return Is(err, CheckViolation)
+}
+
+// IsIntegrityConstraintViolation reports whether err is any integrity
+// constraint violation, the class of unique, foreign key, not null, check
+// and exclusion constraint violations.
+func IsIntegrityConstraintViolation(err error) bool {
+	return IsClass(err, "23")
+}
+
+// IsSerializationFailure reports whether err is a serialization failure of
+// a repeatable read or serializable transaction.
+func IsSerializationFailure(err error) bool {
+	return Is(err, SerializationFailure)
+}
+
+// IsDeadlock reports whether err is a detected deadlock.
+func IsDeadlock(err error) bool {
+	return Is(err, DeadlockDetected)
+}
+
+// IsQueryCanceled reports whether err is a query canceled by the user or by
+// statement_timeout.
+func IsQueryCanceled(err error) bool {
+	return Is(err, QueryCanceled)
+}
+
+// IsRetryable reports whether the transaction that failed with err can
+// succeed when run again, which is the case for serialization failures and
+// deadlocks.
+func IsRetryable(err error) bool {
+	c, _ := SQLState(err)
+	return c == SerializationFailure || c == DeadlockDetected
+}
+
+// IsConnectionError reports whether err means the connection to the server
+// was lost or could not be made. That is the case for connection exceptions,
+// for the server shutting down or not accepting connections yet, and for
+// driver.ErrBadConn, network errors and unexpected EOFs. A canceled or
+// expired context is not a connection error, even though
+// context.DeadlineExceeded is a net.Error, since the connection is fine.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return true
+	}
+	switch c, _ := SQLState(err); c {
+	case AdminShutdown, CrashShutdown, CannotConnectNow:
+		return true
+	default:
+		return IsClass(err, "08")
+	}
+}
+
+// walk calls fn for err and every error it wraps, depth first, until fn
+// returns true.
+func walk(err error, fn func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if fn(err) {
+		return true
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return walk(e.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if walk(err, fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sqlState returns the SQLSTATE code carried by err itself, or an empty
+// string.
+func sqlState(err error) string {
+	if e, ok := err.(interface{ SQLState() string }); ok {
+		return e.SQLState()
+	}
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName("Code")
+	if !f.IsValid() || f.Kind() != reflect.String || len(f.String()) != 5 {
+		return ""
+	}
+	return f.String()
+}
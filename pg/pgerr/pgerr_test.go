@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgerr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+// methodError carries its code like the errors of pgx.
+type methodError struct {
+	code string
+}
+
+func (e *methodError) Error() string    { return "ERROR: (SQLSTATE " + e.code + ")" }
+func (e *methodError) SQLState() string { return e.code }
+
+// errorCode is a named string type, like pq.ErrorCode.
+type errorCode string
+
+// fieldError carries its code like the errors of lib/pq.
+type fieldError struct {
+	Severity string
+	Code     errorCode
+}
+
+func (e *fieldError) Error() string { return "pq: " + string(e.Code) }
+
+// fieldErrorValue carries its code in a field of a non-pointer error.
+type fieldErrorValue struct {
+	Code string
+}
+
+func (e fieldErrorValue) Error() string { return e.Code }
+
+func TestSQLState(t *testing.T) {
+	type test struct {
+		name string
+		err  error
+		want string
+	}
+	tests := []test{
+		{name: "nil"},
+		{name: "plain error", err: errors.New("boom")},
+		{name: "method", err: &methodError{code: "23505"}, want: "23505"},
+		{name: "field", err: &fieldError{Code: "40P01"}, want: "40P01"},
+		{name: "field value", err: fieldErrorValue{Code: "40001"}, want: "40001"},
+		{name: "invalid field", err: &fieldError{Code: "E1"}},
+		{name: "nil pointer", err: (*fieldError)(nil)},
+		{name: "wrapped", err: fmt.Errorf("insert: %w", &methodError{code: "23503"}), want: "23503"},
+		{name: "joined", err: errors.Join(errors.New("a"), &fieldError{Code: "08006"}), want: "08006"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := SQLState(tc.err)
+			if got != tc.want || ok != (tc.want != "") {
+				t.Errorf("expected %q, got %q, %v instead", tc.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestClassAndCondition(t *testing.T) {
+	type test struct {
+		code      string
+		class     string
+		condition string
+	}
+	tests := []test{
+		{UniqueViolation, "Integrity Constraint Violation", "unique_violation"},
+		{SerializationFailure, "Transaction Rollback", "serialization_failure"},
+		{"08001", "Connection Exception", "sqlclient_unable_to_establish_sqlconnection"},
+		{"P0000", "PL/pgSQL Error", "plpgsql_error"},
+		{"22001", "Data Exception", "string_data_right_truncation"},
+		{"02000", "No Data", "no_data"},
+		{"23999", "Integrity Constraint Violation", ""},
+		{"ZZ000", "", ""},
+		{"23", "", ""},
+	}
+	for _, tc := range tests {
+		if got := Class(tc.code); got != tc.class {
+			t.Errorf("%s: expected class %q, got %q instead", tc.code, tc.class, got)
+		}
+		if got := Condition(tc.code); got != tc.condition {
+			t.Errorf("%s: expected condition %q, got %q instead", tc.code, tc.condition, got)
+		}
+	}
+	for code := range conditions {
+		if Class(code) == "" {
+			t.Errorf("%s: expected every code to have a class", code)
+		}
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	coded := func(code string) error {
+		return fmt.Errorf("query: %w", &methodError{code: code})
+	}
+	type test struct {
+		name string
+		fn   func(error) bool
+		yes  []error
+		no   []error
+	}
+	tests := []test{
+		{"IsUniqueViolation", IsUniqueViolation, []error{coded("23505")}, []error{coded("23503"), nil}},
+		{"IsForeignKeyViolation", IsForeignKeyViolation, []error{coded("23503")}, []error{coded("23505")}},
+		{"IsNotNullViolation", IsNotNullViolation, []error{coded("23502")}, []error{coded("23505")}},
+		{"IsCheckViolation", IsCheckViolation, []error{coded("23514")}, []error{coded("23505")}},
+		{"IsIntegrityConstraintViolation", IsIntegrityConstraintViolation, []error{coded("23505"), coded("23P01")}, []error{coded("22001")}},
+		{"IsSerializationFailure", IsSerializationFailure, []error{coded("40001")}, []error{coded("40P01")}},
+		{"IsDeadlock", IsDeadlock, []error{coded("40P01")}, []error{coded("40001")}},
+		{"IsQueryCanceled", IsQueryCanceled, []error{coded("57014")}, []error{coded("57P01")}},
+		{"IsRetryable", IsRetryable, []error{coded("40001"), coded("40P01")}, []error{coded("40002"), coded("23505"), errors.New("boom")}},
+		{
+			"IsConnectionError", IsConnectionError,
+			[]error{
+				coded("08006"), coded("57P01"), coded("57P03"),
+				fmt.Errorf("exec: %w", driver.ErrBadConn), io.ErrUnexpectedEOF,
+				&net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			},
+			[]error{
+				coded("57014"), coded("23505"), io.EOF, nil,
+				context.Canceled, fmt.Errorf("query: %w", context.DeadlineExceeded),
+			},
+		},
+	}
+	for _, tc := range tests {
+		for _, err := range tc.yes {
+			if !tc.fn(err) {
+				t.Errorf("%s: expected true for %v", tc.name, err)
+			}
+		}
+		for _, err := range tc.no {
+			if tc.fn(err) {
+				t.Errorf("%s: expected false for %v", tc.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrInsecurePassFile is passed, wrapped with the path, to Resolver.OnWarning
+// when the password file can be read or written by the group or other users.
+// Like libpq, the file is then skipped rather than failing the resolution.
+var ErrInsecurePassFile = errors.New("pg: password file has group or world access")
+
+// ErrAmbiguousPassword is returned, wrapped with the path, when the hosts of
+// a multi-host config match entries of the password file with different
+// passwords, or when only some of them match an entry.
+var ErrAmbiguousPassword = errors.New("pg: hosts have different passwords in password file")
+
+// passEntry is a line of a password file, in the format
+// hostname:port:database:username:password.
+type passEntry struct {
+	fields [5]string
+}
+
+// matches reports whether the entry applies to the given connection fields.
+// A field of "*" matches anything.
+func (e passEntry) matches(host, port, database, username string) bool {
+	for i, v := range []string{host, port, database, username} {
+		if e.fields[i] != "*" && e.fields[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvePassword sets the password of cfg from the first entry of the
+// password file matching each of its hosts. A missing password file is not
+// an error.
+// See more at https://www.postgresql.org/docs/13/libpq-pgpass.html.
+func (r Resolver) resolvePassword(cfg *ConnectionConfig) error {
+	path := r.passFile()
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("pg: password file: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		r.warn(fmt.Errorf("%w: %s", ErrInsecurePassFile, path))
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("pg: password file: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := parsePassFile(bufio.NewScanner(f), func(err error) {
+		r.warn(fmt.Errorf("pg: password file %s: %w", path, err))
+	})
+	if err != nil {
+		return fmt.Errorf("pg: password file %s: %w", path, err)
+	}
+
+	username := cfg.User
+	if username == "" {
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+	}
+	database := cfg.Database
+	if database == "" {
+		database = username
+	}
+
+	// Like libpq, the password is looked up for every host. The config holds
+	// a single password that is sent to every host, so the hosts must agree,
+	// including on having no entry at all.
+	var password string
+	for i, addr := range cfg.Addrs() {
+		p := lookupPassword(entries, addr, database, username)
+		if i > 0 && p != password {
+			return fmt.Errorf("%w: %s", ErrAmbiguousPassword, path)
+		}
+		password = p
+	}
+	cfg.Password = password
+	return nil
+}
+
+// lookupPassword returns the password of the first entry matching addr, or
+// an empty string if there is none.
+func lookupPassword(entries []passEntry, addr HostPort, database, username string) string {
+	host := addr.Host
+	if host == "" || strings.HasPrefix(host, "/") {
+		host = "localhost"
+	}
+	port := addr.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	for _, e := range entries {
+		if e.matches(host, strconv.Itoa(port), database, username) {
+			return e.fields[4]
+		}
+	}
+	return ""
+}
+
+// parsePassFile reads the entries of a password file. Blank lines and lines
+// starting with # are skipped, and a backslash escapes a colon or backslash.
+// Like libpq, malformed lines are skipped too, after passing their error to
+// skip.
+func parsePassFile(s *bufio.Scanner, skip func(error)) ([]passEntry, error) {
+	var entries []passEntry
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var (
+			e     passEntry
+			field strings.Builder
+			i     int
+		)
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case c == '\\' && j+1 < len(line):
+				j++
+				field.WriteByte(line[j])
+			case c == ':' && i < len(e.fields)-1:
+				e.fields[i] = field.String()
+				field.Reset()
+				i++
+			default:
+				field.WriteByte(c)
+			}
+		}
+		if i != len(e.fields)-1 {
+			skip(fmt.Errorf("line %d: expected 5 colon separated fields", n))
+			continue
+		}
+		e.fields[i] = field.String()
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
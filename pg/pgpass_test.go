@@ -0,0 +1,204 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParsePassFile(t *testing.T) {
+	file := strings.Join([]string{
+		"# comment",
+		"",
+		`db.internal:5432:orders:app:pa\:ss\\word`,
+		"*:*:*:*:fallback",
+	}, "\n")
+	entries, err := parsePassFile(bufio.NewScanner(strings.NewReader(file)), func(err error) {
+		t.Errorf("unexpected skipped line: %v", err)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %v", len(entries))
+	}
+	if got := entries[0].fields[4]; got != `pa:ss\word` {
+		t.Errorf("expected escaped password, got %q", got)
+	}
+	if !entries[1].matches("any", "1", "db", "user") {
+		t.Error("expected wildcard entry to match anything")
+	}
+
+	var skipped []error
+	entries, err = parsePassFile(bufio.NewScanner(strings.NewReader("host:5432:db\n*:*:*:*:pw")), func(err error) {
+		skipped = append(skipped, err)
+	})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected the malformed line to be skipped, got %v entries and error %v", len(entries), err)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "line 1") {
+		t.Errorf("expected line 1 to be reported, got %v", skipped)
+	}
+}
+
+func TestResolver_Password(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	file := strings.Join([]string{
+		"db.internal:5432:orders:app:orders-secret",
+		"db.internal:*:*:app:app-secret",
+		"localhost:5432:*:*:local-secret",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := Resolver{PassFile: path, ServiceFile: filepath.Join(t.TempDir(), "missing")}
+
+	type test struct {
+		name string
+		cfg  *ConnectionConfig
+		want string
+	}
+
+	tests := []test{
+		{name: "exact match", cfg: &ConnectionConfig{Host: "db.internal", User: "app", Database: "orders"}, want: "orders-secret"},
+		{name: "wildcard port and database", cfg: &ConnectionConfig{Host: "db.internal", Port: 6543, User: "app", Database: "billing"}, want: "app-secret"},
+		{name: "unix socket matches localhost", cfg: &ConnectionConfig{Host: "/var/run/postgresql", User: "other", Database: "x"}, want: "local-secret"},
+		{name: "no match", cfg: &ConnectionConfig{Host: "elsewhere", User: "app"}, want: ""},
+		{name: "password already set", cfg: &ConnectionConfig{Host: "db.internal", User: "app", Password: "explicit"}, want: "explicit"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := r.Resolve(tc.cfg); err != nil {
+				t.Fatalf("%v: unexpected error %v", tc.name, err)
+			}
+			if tc.cfg.Password != tc.want {
+				t.Errorf("%v: expected password %q, got %q instead", tc.name, tc.want, tc.cfg.Password)
+			}
+		})
+	}
+}
+
+func TestResolver_MalformedPassFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	file := strings.Join([]string{
+		"db.internal:5432:orders",
+		"db.internal:5432:orders:app:orders-secret",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var warnings []error
+	r := Resolver{
+		PassFile:    path,
+		ServiceFile: filepath.Join(t.TempDir(), "missing"),
+		OnWarning:   func(err error) { warnings = append(warnings, err) },
+	}
+	cfg := &ConnectionConfig{Host: "db.internal", User: "app", Database: "orders"}
+	if err := r.Resolve(cfg); err != nil {
+		t.Fatalf("expected malformed line to be skipped, got %v", err)
+	}
+	if cfg.Password != "orders-secret" {
+		t.Errorf("expected password %q, got %q instead", "orders-secret", cfg.Password)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected the malformed line to be reported, got %v", warnings)
+	}
+}
+
+func TestResolver_MultiHostPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	file := strings.Join([]string{
+		"a.internal:5432:*:app:shared-secret",
+		"b.internal:5432:*:app:shared-secret",
+		"c.internal:5432:*:app:other-secret",
+		"localhost:5432:*:*:local-secret",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := Resolver{PassFile: path, ServiceFile: filepath.Join(t.TempDir(), "missing")}
+
+	type test struct {
+		name    string
+		dsn     string
+		want    string
+		wantErr error
+	}
+
+	tests := []test{
+		{name: "same password", dsn: "postgres://app@a.internal,b.internal/d", want: "shared-secret"},
+		{name: "different passwords", dsn: "postgres://app@a.internal,c.internal/d", wantErr: ErrAmbiguousPassword},
+		{name: "unmatched host", dsn: "host=a.internal,elsewhere user=app dbname=d", wantErr: ErrAmbiguousPassword},
+		{name: "no match", dsn: "postgres://app@x.internal,y.internal/d", want: ""},
+		{name: "hosts field", dsn: "host=a.internal,b.internal port=5432 user=app", want: "shared-secret"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParseDSN(tc.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.Resolve(cfg)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%v: expected error %v, got %v instead", tc.name, tc.wantErr, err)
+			}
+			if cfg.Password != tc.want {
+				t.Errorf("%v: expected password %q, got %q instead", tc.name, tc.want, cfg.Password)
+			}
+		})
+	}
+}
+
+func TestResolver_InsecurePassFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("password file permissions are not checked on windows")
+	}
+	path := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(path, []byte("*:*:*:*:secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ConnectionConfig{Host: pg}
+	var warnings []error
+	r := Resolver{PassFile: path, OnWarning: func(err error) { warnings = append(warnings, err) }}
+	if err := r.Resolve(cfg); err != nil {
+		t.Errorf("expected insecure password file to be skipped, got %v", err)
+	}
+	if cfg.Password != "" {
+		t.Errorf("expected no password, got %q instead", cfg.Password)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrInsecurePassFile) {
+		t.Errorf("expected ErrInsecurePassFile warning, got %v instead", warnings)
+	}
+	if err := (Resolver{PassFile: path}).Resolve(&ConnectionConfig{Host: pg}); err != nil {
+		t.Errorf("expected no error without OnWarning, got %v", err)
+	}
+}
+
+func TestResolver_MissingPassFile(t *testing.T) {
+	cfg := &ConnectionConfig{Host: pg}
+	if err := (Resolver{PassFile: filepath.Join(t.TempDir(), "missing")}).Resolve(cfg); err != nil {
+		t.Errorf("expected missing password file to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Range is a Postgres range of T, such as an int8range or a tstzrange, which
+// implements sql.Scanner and driver.Valuer with the range text format, such
+// as [1,10) or empty. A bound is infinite when the range is unbounded on that
+// side, and an infinite bound is never inclusive.
+// See more at https://www.postgresql.org/docs/13/rangetypes.html#RANGETYPES-IO.
+type Range[T any] struct {
+	Lower          T
+	Upper          T
+	LowerInclusive bool
+	UpperInclusive bool
+	LowerInfinite  bool
+	UpperInfinite  bool
+	// Empty is set for the empty range, which contains no values and has no
+	// bounds.
+	Empty bool
+	// Null is set for a NULL range, which Value writes back as NULL.
+	Null bool
+}
+
+// Scan implements sql.Scanner. A NULL range is scanned into a Range with only
+// Null set.
+func (r *Range[T]) Scan(src any) error {
+	text, ok, err := srcText(src)
+	if err != nil || !ok {
+		*r = Range[T]{Null: err == nil}
+		return err
+	}
+	var out Range[T]
+	s := strings.TrimSpace(text)
+	if strings.EqualFold(s, "empty") {
+		*r = Range[T]{Empty: true}
+		return nil
+	}
+	if len(s) < 2 || (s[0] != '[' && s[0] != '(') || (s[len(s)-1] != ']' && s[len(s)-1] != ')') {
+		return fmt.Errorf("pg: invalid range %q", text)
+	}
+	out.LowerInclusive = s[0] == '['
+	out.UpperInclusive = s[len(s)-1] == ']'
+
+	s = s[1 : len(s)-1]
+	lower, s, err := readBound(s, ",")
+	if err != nil {
+		return fmt.Errorf("pg: invalid range %q: %w", text, err)
+	}
+	if s == "" || s[0] != ',' {
+		return fmt.Errorf("pg: invalid range %q: expected a comma", text)
+	}
+	upper, s, err := readBound(s[1:], "")
+	if err != nil || s != "" {
+		return fmt.Errorf("pg: invalid range %q", text)
+	}
+
+	for _, b := range []struct {
+		text      *string
+		dst       *T
+		inclusive *bool
+		infinite  *bool
+	}{
+		{lower, &out.Lower, &out.LowerInclusive, &out.LowerInfinite},
+		{upper, &out.Upper, &out.UpperInclusive, &out.UpperInfinite},
+	} {
+		if b.text == nil {
+			*b.infinite, *b.inclusive = true, false
+			continue
+		}
+		if err := parseText(reflect.ValueOf(b.dst).Elem(), b.text); err != nil {
+			return fmt.Errorf("pg: scan range bound: %w", err)
+		}
+	}
+	*r = out
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (r Range[T]) Value() (driver.Value, error) {
+	if r.Null {
+		return nil, nil
+	}
+	if r.Empty {
+		return "empty", nil
+	}
+	var b strings.Builder
+	if r.LowerInclusive && !r.LowerInfinite {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	for i, bound := range []struct {
+		value    T
+		infinite bool
+	}{
+		{r.Lower, r.LowerInfinite},
+		{r.Upper, r.UpperInfinite},
+	} {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if bound.infinite {
+			continue
+		}
+		s, ok, err := formatText(bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("pg: range bound: %w", err)
+		}
+		if !ok {
+			// A NULL bound is infinite, like in Postgres.
+			continue
+		}
+		b.WriteString(quoteText(s))
+	}
+	if r.UpperInclusive && !r.UpperInfinite {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// readBound reads the range bound at the start of s, up to one of the stop
+// characters, and returns it with the rest of s. An empty, unquoted bound is
+// infinite, and returned as nil.
+func readBound(s, stop string) (*string, string, error) {
+	var (
+		b      strings.Builder
+		quoted bool
+	)
+	for len(s) > 0 && !strings.ContainsRune(stop, rune(s[0])) {
+		switch s[0] {
+		case '"':
+			part, rest, err := readQuoted(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+			b.WriteString(part)
+			s, quoted = rest, true
+		case '\\':
+			if len(s) > 1 {
+				b.WriteByte(s[1])
+				s = s[2:]
+			} else {
+				s = s[1:]
+			}
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	if b.Len() == 0 && !quoted {
+		return nil, s, nil
+	}
+	bound := b.String()
+	return &bound, s, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRange_Scan(t *testing.T) {
+	type test struct {
+		text    string
+		want    Range[int64]
+		wantErr bool
+	}
+	tests := []test{
+		{text: "[1,10)", want: Range[int64]{Lower: 1, Upper: 10, LowerInclusive: true}},
+		{text: "(1,10]", want: Range[int64]{Lower: 1, Upper: 10, UpperInclusive: true}},
+		{text: "(,10)", want: Range[int64]{Upper: 10, LowerInfinite: true}},
+		{text: "[1,)", want: Range[int64]{Lower: 1, UpperInfinite: true, LowerInclusive: true}},
+		{text: "(,)", want: Range[int64]{LowerInfinite: true, UpperInfinite: true}},
+		{text: `["1","10"]`, want: Range[int64]{Lower: 1, Upper: 10, LowerInclusive: true, UpperInclusive: true}},
+		{text: "empty", want: Range[int64]{Empty: true}},
+		{text: "[1,10", wantErr: true},
+		{text: "[1;10)", wantErr: true},
+		{text: "[a,b)", wantErr: true},
+	}
+	for _, tc := range tests {
+		var got Range[int64]
+		err := got.Scan(tc.text)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: expected error %v, got %v instead", tc.text, tc.wantErr, err)
+			continue
+		}
+		if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %+v, got %+v instead", tc.text, tc.want, got)
+		}
+	}
+
+	var quoted Range[string]
+	if err := quoted.Scan(`["a""b","c\,d")`); err != nil {
+		t.Fatal(err)
+	}
+	if quoted.Lower != `a"b` || quoted.Upper != "c,d" {
+		t.Errorf("expected bounds %q and %q, got %+v instead", `a"b`, "c,d", quoted)
+	}
+	var empty Range[string]
+	if err := empty.Scan(`["",z]`); err != nil || empty.LowerInfinite || empty.Lower != "" {
+		t.Errorf("expected a quoted empty bound to be finite, got %+v, %v instead", empty, err)
+	}
+}
+
+func TestRange_Value(t *testing.T) {
+	type test struct {
+		r    Range[int]
+		want string
+	}
+	tests := []test{
+		{Range[int]{Lower: 1, Upper: 10, LowerInclusive: true}, `["1","10")`},
+		{Range[int]{Upper: 10, LowerInfinite: true, LowerInclusive: true, UpperInclusive: true}, `(,"10"]`},
+		{Range[int]{LowerInfinite: true, UpperInfinite: true}, `(,)`},
+		{Range[int]{Empty: true, Lower: 3}, "empty"},
+	}
+	for _, tc := range tests {
+		got, err := tc.r.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("expected %v, got %v instead", tc.want, got)
+		}
+	}
+}
+
+func TestRange_RoundTrip(t *testing.T) {
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	in := Range[time.Time]{Lower: start, Upper: start.Add(time.Hour), LowerInclusive: true}
+	var out Range[time.Time]
+	roundTrip(t, in, &out)
+	if !out.Lower.Equal(in.Lower) || !out.Upper.Equal(in.Upper) || !out.LowerInclusive || out.UpperInclusive {
+		t.Errorf("expected %+v, got %+v instead", in, out)
+	}
+}
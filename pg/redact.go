@@ -0,0 +1,89 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// RedactedPassword replaces the password when a ConnectionConfig is rendered
+// by Redacted, String, GoString or LogValue.
+const RedactedPassword = "********"
+
+// Redacted formats the ConnectionConfig like ODBC, with the password replaced
+// by RedactedPassword so that it is safe to log.
+func (c ConnectionConfig) Redacted() string {
+	masked := c.WithoutSecrets()
+	if c.Password != "" {
+		masked.Password = RedactedPassword
+	}
+	return masked.ODBC()
+}
+
+// RedactedURL formats the ConnectionConfig like URL, with the password
+// replaced by RedactedPassword so that it is safe to log.
+func (c ConnectionConfig) RedactedURL() string {
+	masked := c.WithoutSecrets()
+	if c.Password != "" {
+		masked.Password = RedactedPassword
+	}
+	return masked.URL()
+}
+
+// WithoutSecrets returns a copy of the ConnectionConfig with the password
+// removed. Marshalling the copy to JSON or YAML omits the password field,
+// while marshalling the ConnectionConfig itself keeps it so that it can be
+// loaded back.
+func (c ConnectionConfig) WithoutSecrets() ConnectionConfig {
+	c.Password = ""
+	return c
+}
+
+// String implements fmt.Stringer, returning the redacted connection string.
+func (c ConnectionConfig) String() string {
+	return c.Redacted()
+}
+
+// GoString implements fmt.GoStringer, so that formatting the ConnectionConfig
+// with %#v does not print the password.
+func (c ConnectionConfig) GoString() string {
+	// plain has the fields but not the methods of ConnectionConfig, so
+	// formatting it does not recurse into GoString.
+	type plain ConnectionConfig
+	if c.Password != "" {
+		c.Password = RedactedPassword
+	}
+	return strings.Replace(fmt.Sprintf("%#v", plain(c)), "pg.plain", "pg.ConnectionConfig", 1)
+}
+
+// LogValue implements slog.LogValuer, logging the connection parameters that
+// are set as a group with the password replaced by RedactedPassword.
+func (c ConnectionConfig) LogValue() slog.Value {
+	var attrs []slog.Attr
+	for _, p := range c.params() {
+		if p.value == "" {
+			continue
+		}
+		if p.key == "password" {
+			p.value = RedactedPassword
+		}
+		attrs = append(attrs, slog.String(p.key, p.value))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const secret = "hunter2 with spaces"
+
+// secretConfig returns a ConnectionConfig with a password set.
+func secretConfig() ConnectionConfig {
+	return ConnectionConfig{
+		Host:     pg,
+		User:     pg,
+		Password: secret,
+		Database: pg,
+		SSLMode:  SSLModeRequire,
+	}
+}
+
+func TestConnectionConfig_Redacted(t *testing.T) {
+	cfg := secretConfig()
+	want := "host=postgres port=5432 user=postgres dbname=postgres password=******** sslmode=require"
+	if got := cfg.Redacted(); got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+	if got := cfg.RedactedURL(); strings.Contains(got, "hunter2") || !strings.Contains(got, "postgres:%2A%2A%2A%2A%2A%2A%2A%2A@") {
+		t.Errorf("expected redacted URL, got %v instead", got)
+	}
+	if cfg.Password != secret {
+		t.Error("expected Redacted to not modify the config")
+	}
+
+	noPassword := ConnectionConfig{Host: pg}
+	if got := noPassword.Redacted(); strings.Contains(got, "password") {
+		t.Errorf("expected no password in %v", got)
+	}
+}
+
+func TestConnectionConfig_Formatting(t *testing.T) {
+	cfg := secretConfig()
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		for _, v := range []any{cfg, &cfg} {
+			got := fmt.Sprintf(format, v)
+			if strings.Contains(got, "hunter2") {
+				t.Errorf("%v: expected password to be redacted, got %v", format, got)
+			}
+			if !strings.Contains(got, RedactedPassword) {
+				t.Errorf("%v: expected redacted password placeholder, got %v", format, got)
+			}
+		}
+	}
+	if got := fmt.Sprintf("%#v", cfg); !strings.HasPrefix(got, "pg.ConnectionConfig{") {
+		t.Errorf("expected GoString to name the type, got %v", got)
+	}
+}
+
+func TestConnectionConfig_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	cfg := secretConfig()
+	logger.Info("connecting", "db", cfg)
+
+	got := buf.String()
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("expected password to be redacted, got %v", got)
+	}
+	if !strings.Contains(got, "db.host=postgres") || !strings.Contains(got, "db.password="+RedactedPassword) {
+		t.Errorf("expected grouped connection attributes, got %v", got)
+	}
+}
+
+func TestConnectionConfig_Marshal(t *testing.T) {
+	cfg := secretConfig()
+
+	full, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded ConnectionConfig
+	if err := json.Unmarshal(full, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Password != secret {
+		t.Errorf("expected password to round trip through JSON, got %q", loaded.Password)
+	}
+
+	public, err := json.Marshal(cfg.WithoutSecrets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(public, []byte("password")) {
+		t.Errorf("expected password to be omitted, got %s", public)
+	}
+}
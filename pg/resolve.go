@@ -0,0 +1,123 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Resolver fills in the fields of a ConnectionConfig that are not set from
+// the libpq connection service file and password file. Empty fields of the
+// Resolver fall back to the libpq environment variables and default paths.
+// The hosts of a multi-host config must agree on their password, as described
+// on Resolve.
+type Resolver struct {
+	// PassFile is the password file, defaulting to PGPASSFILE or ~/.pgpass.
+	PassFile string
+	// ServiceFile is the per-user service file, defaulting to PGSERVICEFILE
+	// or ~/.pg_service.conf.
+	ServiceFile string
+	// SysServiceFile is the system-wide service file, which is consulted
+	// when the service is not in ServiceFile. It defaults to
+	// pg_service.conf in PGSYSCONFDIR, and is not used if that is not set.
+	SysServiceFile string
+	// OnWarning is called with the problems that are skipped rather than
+	// failing the resolution, such as ErrInsecurePassFile. It may be nil.
+	OnWarning func(error)
+}
+
+// Resolve fills in the fields of cfg that are not set, using a Resolver with
+// the default libpq files.
+func Resolve(cfg *ConnectionConfig) error {
+	return Resolver{}.Resolve(cfg)
+}
+
+// Resolve fills in the fields of cfg that are not set. If cfg names a
+// Service, its parameters are read from the service files; fields already
+// set in cfg take precedence over them, and Service is cleared since it has
+// been resolved. Then, if cfg has no Password, the first matching entry of
+// the password file is used.
+//
+// Unlike libpq, which looks up the password when connecting to each host, cfg
+// holds a single password for all of its hosts. Resolve therefore returns
+// ErrAmbiguousPassword if the hosts of cfg do not all match entries with the
+// same password, including when only some of them match an entry; set the
+// Password of such configs explicitly.
+func (r Resolver) Resolve(cfg *ConnectionConfig) error {
+	if cfg.Service != "" {
+		if err := r.resolveService(cfg); err != nil {
+			return err
+		}
+		cfg.Service = ""
+	}
+	if cfg.Password == "" {
+		return r.resolvePassword(cfg)
+	}
+	return nil
+}
+
+// warn passes err to OnWarning, if it is set.
+func (r Resolver) warn(err error) {
+	if r.OnWarning != nil {
+		r.OnWarning(err)
+	}
+}
+
+// passFile returns the path of the password file.
+func (r Resolver) passFile() string {
+	if r.PassFile != "" {
+		return r.PassFile
+	}
+	if v := os.Getenv("PGPASSFILE"); v != "" {
+		return v
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "postgresql", "pgpass.conf")
+	}
+	return homeFile(".pgpass")
+}
+
+// serviceFiles returns the paths of the per-user and system-wide service
+// files, either of which may be empty.
+func (r Resolver) serviceFiles() (string, string) {
+	user := r.ServiceFile
+	if user == "" {
+		user = os.Getenv("PGSERVICEFILE")
+	}
+	if user == "" {
+		user = homeFile(".pg_service.conf")
+	}
+	sys := r.SysServiceFile
+	if sys == "" {
+		if dir := os.Getenv("PGSYSCONFDIR"); dir != "" {
+			sys = filepath.Join(dir, "pg_service.conf")
+		}
+	}
+	return user, sys
+}
+
+// homeFile returns the path of name in the home directory, or an empty
+// string if the home directory is unknown.
+func homeFile(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, name)
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradleybonitatibus/rig/pg/pgerr"
+)
+
+// DefaultReplicaCooldown is how long a replica is ejected for after failing
+// a health check, when RouterOptions.Cooldown is not set.
+const DefaultReplicaCooldown = 30 * time.Second
+
+// BalancePolicy chooses which healthy replica a Router sends a read to.
+type BalancePolicy int
+
+const (
+	// RoundRobin sends reads to each healthy replica in turn.
+	RoundRobin BalancePolicy = iota
+	// LeastInFlight sends reads to the healthy replica with the fewest
+	// connections in use.
+	LeastInFlight
+)
+
+// RouterOptions configures a Router.
+type RouterOptions struct {
+	// Policy chooses the replica each read is sent to.
+	Policy BalancePolicy
+	// Cooldown is how long a replica that failed a health check is ejected
+	// for, defaulting to DefaultReplicaCooldown.
+	Cooldown time.Duration
+	// HealthCheckInterval is how often the replicas are pinged in the
+	// background. Zero disables background health checks, in which case
+	// HealthCheck can be called directly.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds each ping, defaulting to DefaultPingTimeout.
+	HealthCheckTimeout time.Duration
+}
+
+// replica is a read-only pool of a Router, and the time until which it is
+// ejected.
+type replica struct {
+	db           *sql.DB
+	ejectedUntil atomic.Int64
+}
+
+// Router splits reads and writes between a primary pool and replica pools.
+// Writes and transactions are sent to the primary, and reads are balanced
+// across the replicas that are not ejected. When every replica is ejected,
+// reads fall back to the primary.
+type Router struct {
+	primary  *sql.DB
+	replicas []*replica
+	opts     RouterOptions
+	next     atomic.Uint64
+	now      func() time.Time
+
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewRouter creates a Router over the primary pool and the replica pools. If
+// opts.HealthCheckInterval is set, the replicas are health checked in the
+// background until Close is called.
+func NewRouter(primary *sql.DB, replicas []*sql.DB, opts RouterOptions) *Router {
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultReplicaCooldown
+	}
+	if opts.HealthCheckTimeout <= 0 {
+		opts.HealthCheckTimeout = DefaultPingTimeout
+	}
+	r := &Router{
+		primary:  primary,
+		replicas: make([]*replica, len(replicas)),
+		opts:     opts,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	for i, db := range replicas {
+		r.replicas[i] = &replica{db: db}
+	}
+	if opts.HealthCheckInterval > 0 {
+		r.done.Add(1)
+		go r.healthCheckLoop()
+	}
+	return r
+}
+
+// Primary returns the primary pool.
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+// Replica returns the pool the next read is sent to, which is the primary if
+// there is no healthy replica.
+func (r *Router) Replica() *sql.DB {
+	if rep := r.pick(); rep != nil {
+		return rep.db
+	}
+	return r.primary
+}
+
+// ExecContext executes a write on the primary.
+func (r *Router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on the primary.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// QueryContext executes a read-only query on a healthy replica. A replica
+// whose connection turns out to be broken, as reported by
+// pgerr.IsConnectionError, is ejected. Queries that write,
+// such as INSERT ... RETURNING, must be run on Primary instead.
+func (r *Router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rep := r.pick()
+	if rep == nil {
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+	rows, err := rep.db.QueryContext(ctx, query, args...)
+	if pgerr.IsConnectionError(err) {
+		r.eject(rep)
+	}
+	return rows, err
+}
+
+// QueryRowContext executes a read-only query that returns at most one row on
+// a healthy replica, ejecting it on a connection error like QueryContext.
+// Queries that write must be run on Primary instead.
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	rep := r.pick()
+	if rep == nil {
+		return r.primary.QueryRowContext(ctx, query, args...)
+	}
+	row := rep.db.QueryRowContext(ctx, query, args...)
+	if pgerr.IsConnectionError(row.Err()) {
+		r.eject(rep)
+	}
+	return row
+}
+
+// HealthCheck pings every replica, ejecting the ones that fail for the
+// cooldown period. It returns the number of replicas that are healthy.
+func (r *Router) HealthCheck(ctx context.Context) int {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, r.opts.HealthCheckTimeout)
+			defer cancel()
+			if err := rep.db.PingContext(ctx); err != nil {
+				r.eject(rep)
+			}
+		}(rep)
+	}
+	wg.Wait()
+	return len(r.healthy())
+}
+
+// Close stops the background health checks and closes every pool.
+func (r *Router) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	r.done.Wait()
+	errs := []error{r.primary.Close()}
+	for _, rep := range r.replicas {
+		errs = append(errs, rep.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// healthCheckLoop runs HealthCheck every HealthCheckInterval until Close is
+// called.
+func (r *Router) healthCheckLoop() {
+	defer r.done.Done()
+	ticker := time.NewTicker(r.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.stop
+		cancel()
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			r.HealthCheck(ctx)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// eject takes rep out of rotation for the cooldown period.
+func (r *Router) eject(rep *replica) {
+	rep.ejectedUntil.Store(r.now().Add(r.opts.Cooldown).UnixNano())
+}
+
+// healthy returns the replicas that are not ejected.
+func (r *Router) healthy() []*replica {
+	now := r.now().UnixNano()
+	var out []*replica
+	for _, rep := range r.replicas {
+		if rep.ejectedUntil.Load() <= now {
+			out = append(out, rep)
+		}
+	}
+	return out
+}
+
+// pick returns the healthy replica chosen by the balance policy, or nil if
+// there is none.
+func (r *Router) pick() *replica {
+	healthy := r.healthy()
+	if len(healthy) == 0 {
+		return nil
+	}
+	if r.opts.Policy == LeastInFlight {
+		best := healthy[0]
+		for _, rep := range healthy[1:] {
+			if rep.db.Stats().InUse < best.db.Stats().InUse {
+				best = rep
+			}
+		}
+		return best
+	}
+	return healthy[(r.next.Add(1)-1)%uint64(len(healthy))]
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// routerFixture opens a primary and two replica pools on a fakeDriver that
+// records which pool every statement was sent to.
+type routerFixture struct {
+	d    *fakeDriver
+	mu   sync.Mutex
+	sent map[string]int
+	down map[string]bool
+}
+
+func newRouterFixture(t *testing.T) (*routerFixture, *sql.DB, []*sql.DB) {
+	t.Helper()
+	f := &routerFixture{sent: map[string]int{}, down: map[string]bool{}}
+	d, name := newFakeDriver(t)
+	f.d = d
+	d.ping = func(dsn string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.down[dsn] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	record := func(dsn string) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.sent[dsn]++
+	}
+	d.query = func(dsn, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+		record(dsn)
+		return &fakeRows{columns: []string{"v"}, values: [][]driver.Value{{int64(1)}}}, nil
+	}
+	d.exec = func(dsn, _ string, _ []driver.NamedValue) (driver.Result, error) {
+		record(dsn)
+		return driver.RowsAffected(1), nil
+	}
+
+	open := func(dsn string) *sql.DB {
+		db, err := sql.Open(name, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	return f, open("primary"), []*sql.DB{open("r1"), open("r2")}
+}
+
+func (f *routerFixture) count(dsn string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sent[dsn]
+}
+
+func (f *routerFixture) setDown(dsn string, down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down[dsn] = down
+}
+
+func TestRouter_SplitsReadsAndWrites(t *testing.T) {
+	f, primary, replicas := newRouterFixture(t)
+	r := NewRouter(primary, replicas, RouterOptions{})
+	defer r.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		rows, err := r.QueryContext(ctx, "SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+	if _, err := r.ExecContext(ctx, "UPDATE t SET v = 1"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := r.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.count("r1") != 5 || f.count("r2") != 5 {
+		t.Errorf("expected reads to be round robined, got r1=%v r2=%v", f.count("r1"), f.count("r2"))
+	}
+	if f.count("primary") != 2 {
+		t.Errorf("expected writes and transactions on the primary, got %v", f.count("primary"))
+	}
+}
+
+func TestRouter_EjectsUnhealthyReplicas(t *testing.T) {
+	f, primary, replicas := newRouterFixture(t)
+	r := NewRouter(primary, replicas, RouterOptions{Cooldown: time.Minute})
+	defer r.Close()
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	f.setDown("r1", true)
+	if n := r.HealthCheck(ctx); n != 1 {
+		t.Errorf("expected 1 healthy replica, got %v", n)
+	}
+	for i := 0; i < 4; i++ {
+		if r.Replica() != replicas[1] {
+			t.Error("expected reads to skip the ejected replica")
+		}
+	}
+
+	f.setDown("r2", true)
+	if n := r.HealthCheck(ctx); n != 0 {
+		t.Errorf("expected no healthy replicas, got %v", n)
+	}
+	if r.Replica() != primary {
+		t.Error("expected reads to fall back to the primary")
+	}
+
+	f.setDown("r1", false)
+	f.setDown("r2", false)
+	now = now.Add(time.Minute)
+	if n := r.HealthCheck(ctx); n != 2 {
+		t.Errorf("expected replicas to return after the cooldown, got %v healthy", n)
+	}
+}
+
+func TestRouter_EjectsOnConnectionErrors(t *testing.T) {
+	type test struct {
+		name  string
+		err   error
+		eject bool
+	}
+	tests := []test{
+		{name: "bad connection", err: driver.ErrBadConn, eject: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded},
+		{name: "canceled", err: context.Canceled},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, primary, replicas := newRouterFixture(t)
+			r := NewRouter(primary, replicas, RouterOptions{})
+			defer r.Close()
+			f.d.query = func(string, string, []driver.NamedValue) (driver.Rows, error) {
+				return nil, tc.err
+			}
+			if _, err := r.QueryContext(context.Background(), "SELECT 1"); !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v instead", tc.err, err)
+			}
+			if err := r.QueryRowContext(context.Background(), "SELECT 1").Err(); !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v instead", tc.err, err)
+			}
+			want := 2
+			if tc.eject {
+				want = 0
+			}
+			if n := len(r.healthy()); n != want {
+				t.Errorf("expected %v healthy replicas, got %v instead", want, n)
+			}
+		})
+	}
+}
+
+func TestRouter_LeastInFlight(t *testing.T) {
+	_, primary, replicas := newRouterFixture(t)
+	r := NewRouter(primary, replicas, RouterOptions{Policy: LeastInFlight})
+	defer r.Close()
+	ctx := context.Background()
+
+	// Holding rows open keeps a connection of r1 in use.
+	rows, err := replicas[0].QueryContext(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for i := 0; i < 3; i++ {
+		if r.Replica() != replicas[1] {
+			t.Error("expected reads to go to the replica with fewer connections in use")
+		}
+	}
+}
+
+func TestRouter_BackgroundHealthCheck(t *testing.T) {
+	f, primary, replicas := newRouterFixture(t)
+	f.setDown("r2", true)
+	r := NewRouter(primary, replicas, RouterOptions{HealthCheckInterval: time.Millisecond})
+
+	deadline := time.Now().Add(time.Second)
+	for len(r.healthy()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(r.healthy()) != 1 {
+		t.Error("expected background health check to eject r2")
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error closing router: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// txKey is the context key of the transaction passed to the function run by
+// WithTx and Tx.Savepoint.
+type txKey struct{}
+
+// TxFromContext returns the innermost transaction carried by ctx. If ctx was
+// not passed down from WithTx or Tx.Savepoint, it will return nil, and false.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// withTx returns a copy of ctx carrying tx.
+func withTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Nested reports whether the transaction runs in a savepoint of an enclosing
+// transaction.
+func (tx *Tx) Nested() bool {
+	return tx.savepoint != ""
+}
+
+// Savepoint runs fn in a nested transaction. A SAVEPOINT with a generated
+// name is issued before fn is run, and released if fn returns nil. If fn
+// returns an error or panics, the work done by fn is undone with ROLLBACK TO
+// SAVEPOINT, while the enclosing transaction can carry on. A panic is
+// propagated after the rollback.
+//
+// The ctx passed to fn carries the nested transaction, so WithTx nests
+// further savepoints when called with it.
+func (tx *Tx) Savepoint(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	name := fmt.Sprintf("rig_savepoint_%d", tx.savepoints.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("pg: savepoint: %w", err)
+	}
+	nested := &Tx{Tx: tx.Tx, db: tx.db, savepoint: name, savepoints: tx.savepoints}
+
+	released := false
+	defer func() {
+		if released {
+			return
+		}
+		if p := recover(); p != nil {
+			nested.rollbackToSavepoint(ctx)
+			panic(p)
+		}
+		if rbErr := nested.rollbackToSavepoint(ctx); rbErr != nil {
+			err = errors.Join(err, rbErr)
+		}
+	}()
+
+	if err := fn(withTx(ctx, nested), nested); err != nil {
+		return err
+	}
+	released = true
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("pg: release savepoint: %w", err)
+	}
+	return nil
+}
+
+// rollbackToSavepoint undoes the work of a nested transaction, and releases
+// its savepoint. It is not interrupted by the cancellation of ctx, as the
+// enclosing transaction may still be committed.
+func (tx *Tx) rollbackToSavepoint(ctx context.Context) error {
+	ctx = context.WithoutCancel(ctx)
+	if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+tx.savepoint); err != nil {
+		return fmt.Errorf("pg: rollback to savepoint: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+tx.savepoint); err != nil {
+		return fmt.Errorf("pg: release savepoint: %w", err)
+	}
+	return nil
+}
+
+// beganOn reports whether the transaction was started on db. Values of
+// types that cannot be compared, which no database/sql type is, never match.
+func (tx *Tx) beganOn(db TxBeginner) bool {
+	if db == nil {
+		return false
+	}
+	if !reflect.TypeOf(db).Comparable() || reflect.TypeOf(tx.db) != reflect.TypeOf(db) {
+		return false
+	}
+	return tx.db == db
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTx_Savepoint(t *testing.T) {
+	type test struct {
+		name  string
+		inner error
+		log   []string
+	}
+	tests := []test{
+		{
+			name: "releases on success",
+			log: []string{
+				"BEGIN", "SAVEPOINT rig_savepoint_1", "INSERT",
+				"RELEASE SAVEPOINT rig_savepoint_1", "COMMIT",
+			},
+		},
+		{
+			name:  "rolls back to savepoint on error",
+			inner: errors.New("boom"),
+			log: []string{
+				"BEGIN", "SAVEPOINT rig_savepoint_1", "INSERT",
+				"ROLLBACK TO SAVEPOINT rig_savepoint_1", "RELEASE SAVEPOINT rig_savepoint_1", "COMMIT",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d, db := newTxFixture(t)
+			ctx := context.Background()
+			err := WithTx(ctx, db, nil, func(ctx context.Context, outer *Tx) error {
+				err := WithTx(ctx, db, nil, func(ctx context.Context, inner *Tx) error {
+					if !inner.Nested() || outer.Nested() {
+						t.Error("expected only the inner transaction to be nested")
+					}
+					if tx, _ := TxFromContext(ctx); tx != inner {
+						t.Error("expected ctx to carry the inner transaction")
+					}
+					if _, err := inner.ExecContext(ctx, "INSERT"); err != nil {
+						return err
+					}
+					return tc.inner
+				})
+				if err != tc.inner {
+					t.Errorf("expected %v, got %v instead", tc.inner, err)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.statements(); !reflect.DeepEqual(got, tc.log) {
+				t.Errorf("expected %v, got %v instead", tc.log, got)
+			}
+		})
+	}
+}
+
+func TestTx_SavepointNesting(t *testing.T) {
+	d, db := newTxFixture(t)
+	err := WithTx(context.Background(), db, nil, func(ctx context.Context, tx *Tx) error {
+		return tx.Savepoint(ctx, func(ctx context.Context, tx *Tx) error {
+			if err := WithTx(ctx, db, nil, func(context.Context, *Tx) error { return nil }); err != nil {
+				return err
+			}
+			return tx.Savepoint(ctx, func(context.Context, *Tx) error { return nil })
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN", "SAVEPOINT rig_savepoint_1",
+		"SAVEPOINT rig_savepoint_2", "RELEASE SAVEPOINT rig_savepoint_2",
+		"SAVEPOINT rig_savepoint_3", "RELEASE SAVEPOINT rig_savepoint_3",
+		"RELEASE SAVEPOINT rig_savepoint_1", "COMMIT",
+	}
+	if got := d.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
+
+func TestTx_SavepointPanic(t *testing.T) {
+	d, db := newTxFixture(t)
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("expected panic to propagate, got %v instead", p)
+		}
+		want := []string{
+			"BEGIN", "SAVEPOINT rig_savepoint_1",
+			"ROLLBACK TO SAVEPOINT rig_savepoint_1", "RELEASE SAVEPOINT rig_savepoint_1", "ROLLBACK",
+		}
+		if got := d.statements(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v instead", want, got)
+		}
+	}()
+	WithTx(context.Background(), db, nil, func(ctx context.Context, tx *Tx) error {
+		return tx.Savepoint(ctx, func(context.Context, *Tx) error {
+			panic("boom")
+		})
+	})
+}
+
+func TestWithTx_OtherDBIsNotNested(t *testing.T) {
+	d, db := newTxFixture(t)
+	_, other := newTxFixture(t)
+	err := WithTx(context.Background(), db, nil, func(ctx context.Context, _ *Tx) error {
+		return WithTx(ctx, other, nil, func(_ context.Context, tx *Tx) error {
+			if tx.Nested() {
+				t.Error("expected a separate transaction on another db")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"BEGIN", "COMMIT"}
+	if got := d.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
+
+func TestWithTx_NilDB(t *testing.T) {
+	_, db := newTxFixture(t)
+	err := WithTx(context.Background(), db, nil, func(ctx context.Context, _ *Tx) error {
+		return WithTx(ctx, nil, nil, func(context.Context, *Tx) error {
+			t.Error("expected fn not to run without a db")
+			return nil
+		})
+	})
+	if err == nil {
+		t.Error("expected an error for a nil db")
+	}
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrUnmappedColumn is returned, wrapped with the column name, by ScanAll and
+// ScanOne in strict mode when a column has no matching struct field.
+var ErrUnmappedColumn = errors.New("pg: column has no matching field")
+
+// ScanOptions configures ScanAll and ScanOne.
+type ScanOptions struct {
+	// Strict fails the scan when a column has no matching struct field,
+	// instead of discarding its values.
+	Strict bool
+}
+
+// structMappings caches the structMapping of every struct type scanned or
+// inserted, keyed by reflect.Type.
+var structMappings sync.Map
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// ScanAll scans every row of rows into a T, and closes rows. opts may be nil
+// to use the defaults.
+//
+// If T is a struct, or a pointer to one, each column is scanned into the
+// exported field named by its `db:"name"` tag, or the snake_case form of the
+// field name when it has no tag, such as created_at for CreatedAt. Columns
+// are matched case insensitively, fields tagged `db:"-"` are skipped, and the
+// fields of embedded structs are promoted like in Go, so a column matching
+// several fields at the same depth is ambiguous and left unmapped. Pointer
+// fields are set to nil for NULL values. Other types of T, and structs implementing
+// sql.Scanner such as time.Time, are scanned from a single column.
+//
+// The mapping of every struct type is computed once, and cached.
+func ScanAll[T any](rows *sql.Rows, opts *ScanOptions) ([]T, error) {
+	defer rows.Close()
+	plan, err := newScanPlan[T](rows, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []T
+	for rows.Next() {
+		v, err := plan.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("pg: scan: %w", err)
+	}
+	return out, nil
+}
+
+// ScanOne scans the first row of rows into a T like ScanAll, and closes rows.
+// If there are no rows, it will return the "default" value of type T, and
+// sql.ErrNoRows.
+func ScanOne[T any](rows *sql.Rows, opts *ScanOptions) (T, error) {
+	defer rows.Close()
+	var empty T
+	plan, err := newScanPlan[T](rows, opts)
+	if err != nil {
+		return empty, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return empty, fmt.Errorf("pg: scan: %w", err)
+		}
+		return empty, sql.ErrNoRows
+	}
+	return plan.scan(rows)
+}
+
+// ScanMaps scans every row of rows into a map from column name to value, and
+// closes rows. Values have the types returned by the driver, and NULL is nil.
+func ScanMaps(rows *sql.Rows) ([]map[string]any, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("pg: scan: %w", err)
+	}
+	var out []map[string]any
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("pg: scan: %w", err)
+		}
+		m := make(map[string]any, len(columns))
+		for i, c := range columns {
+			m[c] = values[i]
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("pg: scan: %w", err)
+	}
+	return out, nil
+}
+
+// scanPlan scans the columns of a result set into a T.
+type scanPlan[T any] struct {
+	// fields holds the index path of the struct field of every column, or
+	// nil for discarded columns. It is nil when T is scanned from a single
+	// column.
+	fields [][]int
+	// ptr is set when T is a pointer to the scanned struct.
+	ptr bool
+}
+
+// newScanPlan maps the columns of rows onto T.
+func newScanPlan[T any](rows *sql.Rows, opts *ScanOptions) (*scanPlan[T], error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("pg: scan: %w", err)
+	}
+	plan := &scanPlan[T]{}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Pointer && isStruct(t.Elem()) {
+		t, plan.ptr = t.Elem(), true
+	}
+	if !isStruct(t) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("pg: scan: %d columns cannot be scanned into %s", len(columns), t)
+		}
+		return plan, nil
+	}
+
+	mapping := structMappingOf(t)
+	plan.fields = make([][]int, len(columns))
+	for i, c := range columns {
+		index, ok := mapping.fields[strings.ToLower(c)]
+		if !ok && opts != nil && opts.Strict {
+			return nil, fmt.Errorf("%w: %q in %s", ErrUnmappedColumn, c, t)
+		}
+		plan.fields[i] = index
+	}
+	return plan, nil
+}
+
+// scan scans the current row of rows into a new T.
+func (p *scanPlan[T]) scan(rows *sql.Rows) (T, error) {
+	var out T
+	if p.fields == nil {
+		if err := rows.Scan(&out); err != nil {
+			return out, fmt.Errorf("pg: scan: %w", err)
+		}
+		return out, nil
+	}
+
+	v := reflect.ValueOf(&out).Elem()
+	if p.ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	dest := make([]any, len(p.fields))
+	for i, index := range p.fields {
+		if index == nil {
+			dest[i] = new(any)
+			continue
+		}
+		dest[i] = fieldByIndex(v, index).Addr().Interface()
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return out, fmt.Errorf("pg: scan: %w", err)
+	}
+	return out, nil
+}
+
+// isStruct reports whether t is a struct that is scanned field by field,
+// rather than from a single column.
+func isStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(scannerType)
+}
+
+// fieldByIndex returns the field of v with the index path, allocating the
+// nil embedded struct pointers along the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// structColumn is a struct field mapped to a column.
+type structColumn struct {
+	name  string
+	index []int
+}
+
+// structMapping is the mapping between the columns and the fields of a
+// struct type.
+type structMapping struct {
+	// columns lists the mapped fields in declaration order.
+	columns []structColumn
+	// fields holds the index path of every mapped field, keyed by the lower
+	// case column name.
+	fields map[string][]int
+}
+
+// structMappingOf returns the mapping of the struct type t, whose fields are
+// mapped to the column named by their db tag or their snake_case name.
+func structMappingOf(t reflect.Type) *structMapping {
+	if m, ok := structMappings.Load(t); ok {
+		return m.(*structMapping)
+	}
+	var candidates []structColumn
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag, hasTag := f.Tag.Lookup("db")
+			if tag == "-" {
+				continue
+			}
+			path := append(append([]int{}, index...), i)
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			// Nil pointers to unexported embedded structs cannot be allocated,
+			// so their fields are not promoted.
+			promoted := f.IsExported() || f.Type.Kind() != reflect.Pointer
+			if f.Anonymous && !hasTag && isStruct(ft) && promoted {
+				walk(ft, path)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			name := tag
+			if name == "" {
+				name = snakeCase(f.Name)
+			}
+			candidates = append(candidates, structColumn{name: name, index: path})
+		}
+	}
+	walk(t, nil)
+
+	m := &structMapping{fields: map[string][]int{}}
+	// Like Go field promotion, the shallowest field wins, and a column
+	// mapped by several fields at that depth is ambiguous and left unmapped.
+	ambiguous := map[string]bool{}
+	for _, c := range candidates {
+		key := strings.ToLower(c.name)
+		prev, ok := m.fields[key]
+		switch {
+		case !ok || len(c.index) < len(prev):
+			m.fields[key] = c.index
+			delete(ambiguous, key)
+		case len(c.index) == len(prev):
+			ambiguous[key] = true
+		}
+	}
+	for key := range ambiguous {
+		delete(m.fields, key)
+	}
+	for _, c := range candidates {
+		if reflect.DeepEqual(m.fields[strings.ToLower(c.name)], c.index) {
+			m.columns = append(m.columns, c)
+		}
+	}
+	actual, _ := structMappings.LoadOrStore(t, m)
+	return actual.(*structMapping)
+}
+
+// snakeCase converts a Go field name to snake_case, keeping initialisms
+// together, so that UserID becomes user_id and HTTPServer http_server.
+func snakeCase(name string) string {
+	r := []rune(name)
+	var b strings.Builder
+	for i, c := range r {
+		if unicode.IsUpper(c) && i > 0 {
+			prev := r[i-1]
+			nextLower := i+1 < len(r) && unicode.IsLower(r[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
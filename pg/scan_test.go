@@ -0,0 +1,226 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// queryRows runs a query on a fakeDriver answering with columns and values.
+func queryRows(t *testing.T, columns []string, values ...[]driver.Value) *sql.Rows {
+	t.Helper()
+	d, name := newFakeDriver(t)
+	d.query = func(string, string, []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{columns: columns, values: values}, nil
+	}
+	db, err := sql.Open(name, "scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	rows, err := db.QueryContext(context.Background(), "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+type scanAudit struct {
+	CreatedAt time.Time
+	UpdatedBy *string
+}
+
+// ScanOwner is exported, as nil pointers to unexported embedded structs
+// cannot be allocated.
+type ScanOwner struct {
+	OwnerID int64
+}
+
+type scanUser struct {
+	ID       int64  `db:"id"`
+	Name     string `db:"full_name"`
+	Email    *string
+	Ignored  string `db:"-"`
+	HTTPPort int
+	scanAudit
+	*ScanOwner
+}
+
+func TestScanAll(t *testing.T) {
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := queryRows(t,
+		[]string{"ID", "full_name", "email", "http_port", "created_at", "updated_by", "owner_id", "extra"},
+		[]driver.Value{int64(1), "Ada", "ada@example.com", int64(5432), created, "root", int64(7), "x"},
+		[]driver.Value{int64(2), "Bob", nil, int64(0), created, nil, int64(8), "y"},
+	)
+	got, err := ScanAll[scanUser](rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, root := "ada@example.com", "root"
+	want := []scanUser{
+		{
+			ID: 1, Name: "Ada", Email: &email, HTTPPort: 5432,
+			scanAudit: scanAudit{CreatedAt: created, UpdatedBy: &root},
+			ScanOwner: &ScanOwner{OwnerID: 7},
+		},
+		{
+			ID: 2, Name: "Bob", HTTPPort: 0,
+			scanAudit: scanAudit{CreatedAt: created},
+			ScanOwner: &ScanOwner{OwnerID: 8},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v instead", want, got)
+	}
+}
+
+func TestScanAll_Strict(t *testing.T) {
+	rows := queryRows(t, []string{"id", "extra"}, []driver.Value{int64(1), "x"})
+	_, err := ScanAll[scanUser](rows, &ScanOptions{Strict: true})
+	if !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("expected %v, got %v instead", ErrUnmappedColumn, err)
+	}
+}
+
+type scanCreated struct {
+	CreatedAt time.Time
+	Source    string
+}
+
+type scanUpdated struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// scanEvent embeds two structs at the same depth that both have a
+// created_at column, which is ambiguous like the promoted Go field.
+type scanEvent struct {
+	ID int64
+	scanCreated
+	scanUpdated
+}
+
+func TestScanAll_AmbiguousColumn(t *testing.T) {
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"id", "created_at", "source", "updated_at"}
+	values := []driver.Value{int64(1), created, "api", created}
+
+	got, err := ScanAll[scanEvent](queryRows(t, columns, values), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []scanEvent{{ID: 1, scanCreated: scanCreated{Source: "api"}, scanUpdated: scanUpdated{UpdatedAt: created}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v instead", want, got)
+	}
+
+	_, err = ScanAll[scanEvent](queryRows(t, columns, values), &ScanOptions{Strict: true})
+	if !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("expected %v, got %v instead", ErrUnmappedColumn, err)
+	}
+}
+
+func TestScanAll_Pointers(t *testing.T) {
+	rows := queryRows(t, []string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	got, err := ScanAll[*scanUser](rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Errorf("expected users 1 and 2, got %+v instead", got)
+	}
+}
+
+func TestScanAll_SingleColumn(t *testing.T) {
+	rows := queryRows(t, []string{"name"}, []driver.Value{"a"}, []driver.Value{"b"})
+	got, err := ScanAll[string](rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+
+	rows = queryRows(t, []string{"a", "b"}, []driver.Value{"a", "b"})
+	if _, err := ScanAll[string](rows, nil); err == nil {
+		t.Error("expected several columns to be rejected for a non-struct type")
+	}
+
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows = queryRows(t, []string{"created_at"}, []driver.Value{created})
+	times, err := ScanAll[time.Time](rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 1 || !times[0].Equal(created) {
+		t.Errorf("expected %v, got %v instead", created, times)
+	}
+}
+
+func TestScanOne(t *testing.T) {
+	rows := queryRows(t, []string{"id", "full_name"}, []driver.Value{int64(1), "Ada"}, []driver.Value{int64(2), "Bob"})
+	got, err := ScanOne[scanUser](rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.Name != "Ada" {
+		t.Errorf("expected the first row, got %+v instead", got)
+	}
+
+	rows = queryRows(t, []string{"id"})
+	if _, err := ScanOne[scanUser](rows, nil); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected %v, got %v instead", sql.ErrNoRows, err)
+	}
+}
+
+func TestScanMaps(t *testing.T) {
+	rows := queryRows(t, []string{"id", "name"}, []driver.Value{int64(1), "Ada"}, []driver.Value{int64(2), nil})
+	got, err := ScanMaps(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"id": int64(1), "name": "Ada"},
+		{"id": int64(2), "name": nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"ID":         "id",
+		"UserID":     "user_id",
+		"CreatedAt":  "created_at",
+		"HTTPServer": "http_server",
+		"Address2":   "address2",
+		"V2Name":     "v2_name",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("expected %v, got %v instead", want, got)
+		}
+	}
+}
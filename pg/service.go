@@ -0,0 +1,124 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ErrServiceNotFound is returned when a ConnectionConfig names a Service that
+// is not defined in any service file.
+var ErrServiceNotFound = errors.New("pg: service not found")
+
+// resolveService fills in the fields of cfg that are not set from its Service
+// section. The per-user service file is searched first, and the system-wide
+// file is only used if the service is not defined in it.
+// See more at https://www.postgresql.org/docs/13/libpq-pgservice.html.
+func (r Resolver) resolveService(cfg *ConnectionConfig) error {
+	user, sys := r.serviceFiles()
+	for _, path := range []string{user, sys} {
+		if path == "" {
+			continue
+		}
+		params, found, err := readService(path, cfg.Service)
+		if err != nil {
+			return fmt.Errorf("pg: service file %s: %w", path, err)
+		}
+		if !found {
+			continue
+		}
+		for _, p := range params {
+			if p.key == "service" {
+				return fmt.Errorf("pg: service file %s: nested service definitions are not supported", path)
+			}
+		}
+		svc, err := newConfig(params)
+		if err != nil {
+			return fmt.Errorf("pg: service file %s: %w", path, err)
+		}
+		return cfg.merge(svc)
+	}
+	return fmt.Errorf("%w: %q", ErrServiceNotFound, cfg.Service)
+}
+
+// readService returns the parameters of the named section of a service file,
+// and whether the section was found. A missing file is not an error.
+func readService(path, name string) ([]param, bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var (
+		params  []param
+		found   bool
+		current string
+		s       = bufio.NewScanner(f)
+	)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, false, fmt.Errorf("line %d: invalid section header %q", n, line)
+			}
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || current == name
+		case current == name:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, false, fmt.Errorf("line %d: expected key=value", n)
+			}
+			params = append(params, param{strings.TrimSpace(key), strings.TrimSpace(value)})
+		}
+	}
+	return params, found, s.Err()
+}
+
+// merge sets every connection parameter of cfg that is not set to its value
+// in other. The hosts of other are only used if cfg has none.
+func (c *ConnectionConfig) merge(other *ConnectionConfig) error {
+	set := make(map[string]bool)
+	for _, p := range c.params() {
+		set[p.key] = p.value != ""
+	}
+	for _, p := range other.params() {
+		if p.key == "host" || p.key == "port" || p.value == "" || set[p.key] {
+			continue
+		}
+		if err := c.set(p.key, p.value); err != nil {
+			return err
+		}
+	}
+	if !set["host"] {
+		c.Host, c.Hosts = other.Host, other.Hosts
+	}
+	if !set["port"] && len(c.Hosts) == 0 {
+		c.Port = other.Port
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolver_Service(t *testing.T) {
+	dir := t.TempDir()
+	userFile := filepath.Join(dir, "user.conf")
+	sysFile := filepath.Join(dir, "sys.conf")
+	passFile := filepath.Join(dir, "pgpass")
+
+	writeFile := func(path, contents string, perm os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(userFile, `
+# per-user services
+[orders]
+host = db.internal
+port=6543
+dbname=orders
+sslmode=verify-full
+`, 0o600)
+	writeFile(sysFile, `
+[reporting]
+host=replica.internal
+user=reporter
+application_name=reports
+`, 0o600)
+	writeFile(passFile, "db.internal:6543:orders:app:orders-secret\n", 0o600)
+
+	r := Resolver{PassFile: passFile, ServiceFile: userFile, SysServiceFile: sysFile}
+
+	cfg := &ConnectionConfig{Service: "orders", User: "app", SSLMode: SSLModeRequire}
+	if err := r.Resolve(cfg); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cfg.Host != "db.internal" || cfg.Port != 6543 || cfg.Database != "orders" {
+		t.Errorf("expected service fields to be filled in, got %#v", cfg)
+	}
+	if cfg.SSLMode != SSLModeRequire {
+		t.Errorf("expected explicit sslmode to take precedence, got %v", cfg.SSLMode)
+	}
+	if cfg.Password != "orders-secret" {
+		t.Errorf("expected password from password file, got %q", cfg.Password)
+	}
+	if cfg.Service != "" {
+		t.Errorf("expected resolved service to be cleared, got %q", cfg.Service)
+	}
+
+	cfg = &ConnectionConfig{Service: "reporting"}
+	if err := r.Resolve(cfg); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cfg.Host != "replica.internal" || cfg.User != "reporter" || cfg.ApplicationName != "reports" {
+		t.Errorf("expected system service fields to be filled in, got %#v", cfg)
+	}
+
+	if err := r.Resolve(&ConnectionConfig{Service: "missing"}); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("expected ErrServiceNotFound, got %v", err)
+	}
+}
+
+func TestResolver_InvalidService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.conf")
+	if err := os.WriteFile(path, []byte("[bad]\nport=abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := Resolver{ServiceFile: path, PassFile: filepath.Join(t.TempDir(), "missing")}
+	if err := r.Resolve(&ConnectionConfig{Service: "bad"}); !errors.Is(err, ErrInvalidDSN) {
+		t.Errorf("expected ErrInvalidDSN, got %v", err)
+	}
+}
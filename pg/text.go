@@ -0,0 +1,227 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// textTimeLayouts are the layouts of the date and time types in the Postgres
+// text format, in the order they are tried.
+var textTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// srcText returns the text of a value scanned from the database. If src is
+// NULL, it will return an empty string, and false.
+func srcText(src any) (string, bool, error) {
+	switch s := src.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return s, true, nil
+	case []byte:
+		return string(s), true, nil
+	default:
+		return "", false, fmt.Errorf("pg: cannot scan %T, expected text", src)
+	}
+}
+
+// parseText sets dst from s, a value in the Postgres text format, where nil
+// is NULL. Types implementing sql.Scanner are given the text, and pointers
+// are set to nil for NULL.
+func parseText(dst reflect.Value, s *string) error {
+	if dst.CanAddr() && dst.Addr().Type().Implements(scannerType) {
+		var src any
+		if s != nil {
+			src = *s
+		}
+		return dst.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	if dst.Kind() == reflect.Pointer {
+		if s == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		p := reflect.New(dst.Type().Elem())
+		if err := parseText(p.Elem(), s); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+	if s == nil {
+		return fmt.Errorf("cannot scan NULL into %s", dst.Type())
+	}
+
+	text := *s
+	invalid := func() error {
+		return fmt.Errorf("cannot scan %q into %s", text, dst.Type())
+	}
+	if dst.Type() == timeType {
+		for _, layout := range textTimeLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				dst.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return invalid()
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(text)
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return invalid()
+		}
+		dst.Set(reflect.ValueOf(text))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return invalid()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, dst.Type().Bits())
+		if err != nil {
+			return invalid()
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, dst.Type().Bits())
+		if err != nil {
+			return invalid()
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, dst.Type().Bits())
+		if err != nil {
+			return invalid()
+		}
+		dst.SetFloat(f)
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() != reflect.Uint8 || !strings.HasPrefix(text, `\x`) {
+			return invalid()
+		}
+		b, err := hex.DecodeString(text[2:])
+		if err != nil {
+			return invalid()
+		}
+		dst.SetBytes(b)
+	default:
+		return invalid()
+	}
+	return nil
+}
+
+// formatText renders v in the Postgres text format. If v is NULL, it will
+// return an empty string, and false.
+func formatText(v any) (string, bool, error) {
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return "", false, err
+	}
+	switch x := dv.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return x, true, nil
+	case []byte:
+		// Like database/sql drivers, a nil slice is NULL rather than an
+		// empty bytea.
+		if x == nil {
+			return "", false, nil
+		}
+		return `\x` + hex.EncodeToString(x), true, nil
+	case int64:
+		return strconv.FormatInt(x, 10), true, nil
+	case float64:
+		switch {
+		case math.IsInf(x, 1):
+			return "Infinity", true, nil
+		case math.IsInf(x, -1):
+			return "-Infinity", true, nil
+		case math.IsNaN(x):
+			return "NaN", true, nil
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64), true, nil
+	case bool:
+		if x {
+			return "t", true, nil
+		}
+		return "f", true, nil
+	case time.Time:
+		return x.Format("2006-01-02 15:04:05.999999999Z07:00"), true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported value %T", dv)
+	}
+}
+
+// quoteText surrounds s with double quotes, escaping double quotes and
+// backslashes, as used by the array, range and hstore text formats.
+func quoteText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// readQuoted reads the double quoted text at the start of s, and returns it
+// unescaped with the rest of s. A backslash escapes the next character, and
+// if doubled is set, so does a double quote, as written by range types.
+func readQuoted(s string, doubled bool) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			if doubled && i+1 < len(s) && s[i+1] == '"' {
+				i++
+				b.WriteByte('"')
+				continue
+			}
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted text %q", s)
+}
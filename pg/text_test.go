@@ -0,0 +1,151 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// roundTrip sends v as a query argument through database/sql, and scans the
+// text the driver received back into dst, like a SELECT $1 would.
+func roundTrip(t *testing.T, v any, dst any) {
+	t.Helper()
+	d, name := newFakeDriver(t)
+	d.query = func(_, _ string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{columns: []string{"v"}, values: [][]driver.Value{{args[0].Value}}}, nil
+	}
+	db, err := sql.Open(name, "roundtrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.QueryRowContext(context.Background(), "SELECT $1", v).Scan(dst); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseText(t *testing.T) {
+	text := func(s string) *string { return &s }
+	seven := int64(7)
+	created := time.Date(2022, 1, 2, 3, 4, 5, 600000000, time.FixedZone("", 2*3600))
+	type test struct {
+		name    string
+		dst     any
+		text    *string
+		want    any
+		wantErr bool
+	}
+	tests := []test{
+		{name: "string", dst: new(string), text: text("a b"), want: "a b"},
+		{name: "bool", dst: new(bool), text: text("t"), want: true},
+		{name: "int", dst: new(int32), text: text("-12"), want: int32(-12)},
+		{name: "int overflow", dst: new(int8), text: text("300"), wantErr: true},
+		{name: "uint", dst: new(uint), text: text("12"), want: uint(12)},
+		{name: "float", dst: new(float64), text: text("1.5"), want: 1.5},
+		{name: "bytea", dst: new([]byte), text: text(`\x0102`), want: []byte{1, 2}},
+		{name: "time", dst: new(time.Time), text: text("2022-01-02 03:04:05.6+02"), want: created},
+		{name: "pointer", dst: new(*int64), text: text("7"), want: &seven},
+		{name: "null pointer", dst: new(*int64), want: (*int64)(nil)},
+		{name: "null", dst: new(int64), wantErr: true},
+		{name: "scanner", dst: new(sql.NullInt64), text: text("7"), want: sql.NullInt64{Int64: 7, Valid: true}},
+		{name: "invalid", dst: new(int64), text: text("seven"), wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := reflect.ValueOf(tc.dst).Elem()
+			err := parseText(dst, tc.text)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v instead", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			if got := dst.Interface(); !reflect.DeepEqual(got, tc.want) {
+				if gt, ok := got.(time.Time); !ok || !gt.Equal(tc.want.(time.Time)) {
+					t.Errorf("expected %v, got %v instead", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	type test struct {
+		value any
+		want  string
+		null  bool
+	}
+	tests := []test{
+		{value: "a", want: "a"},
+		{value: 12, want: "12"},
+		{value: 1.5, want: "1.5"},
+		{value: math.Inf(-1), want: "-Infinity"},
+		{value: false, want: "f"},
+		{value: []byte{1, 2}, want: `\x0102`},
+		{value: []byte{}, want: `\x`},
+		{value: []byte(nil), null: true},
+		{value: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), want: "2022-01-02 03:04:05Z"},
+		{value: (*int)(nil), null: true},
+		{value: sql.NullString{}, null: true},
+	}
+	for _, tc := range tests {
+		got, ok, err := formatText(tc.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want || ok == tc.null {
+			t.Errorf("expected %q (null %v), got %q (null %v) instead", tc.want, tc.null, got, !ok)
+		}
+	}
+}
+
+func TestNullRoundTrip(t *testing.T) {
+	type test struct {
+		name string
+		dst  interface {
+			sql.Scanner
+			driver.Valuer
+		}
+	}
+	tests := []test{
+		{name: "range", dst: &Range[int64]{}},
+		{name: "interval", dst: &Interval{}},
+		{name: "jsonb", dst: &JSONB[map[string]any]{}},
+		{name: "hstore", dst: &Hstore{}},
+		{name: "array", dst: &Array[int64]{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.dst.Scan(nil); err != nil {
+				t.Fatal(err)
+			}
+			v, err := tc.dst.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != nil {
+				t.Errorf("expected NULL to be written back as nil, got %#v instead", v)
+			}
+		})
+	}
+}
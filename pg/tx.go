@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradleybonitatibus/rig/pg/pgerr"
+)
+
+const (
+	// DefaultTxMaxAttempts is how many times WithTx runs a transaction that
+	// keeps failing with a retryable error, when TxOptions.MaxAttempts is
+	// not set.
+	DefaultTxMaxAttempts = 3
+	// DefaultTxBaseBackoff is the backoff before the first retry, when
+	// TxOptions.BaseBackoff is not set.
+	DefaultTxBaseBackoff = 10 * time.Millisecond
+	// DefaultTxMaxBackoff caps the backoff between retries, when
+	// TxOptions.MaxBackoff is not set.
+	DefaultTxMaxBackoff = time.Second
+)
+
+// TxBeginner starts transactions. It is implemented by *sql.DB, *sql.Conn,
+// *DB and *Router.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxOptions configures a transaction run by WithTx.
+type TxOptions struct {
+	// Isolation is the isolation level of the transaction, the zero value
+	// uses the server default.
+	Isolation sql.IsolationLevel
+	// ReadOnly starts a read-only transaction.
+	ReadOnly bool
+	// MaxAttempts is the number of times the transaction is run before a
+	// retryable error is returned, defaulting to DefaultTxMaxAttempts.
+	MaxAttempts int
+	// BaseBackoff is the backoff before the first retry, which doubles with
+	// every retry up to MaxBackoff. A random jitter of up to the backoff is
+	// used, so that conflicting transactions do not retry in lock step.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Tx is a transaction started by WithTx. It embeds *sql.Tx, so it can be
+// used anywhere the database/sql API is expected.
+type Tx struct {
+	*sql.Tx
+	db TxBeginner
+	// savepoint is the name of the savepoint of a nested transaction, and
+	// is empty for the outermost transaction.
+	savepoint string
+	// savepoints numbers the savepoints of the outermost transaction and
+	// every transaction nested in it.
+	savepoints *atomic.Int64
+}
+
+// WithTx runs fn in a transaction started on db. The transaction is
+// committed if fn returns nil, and rolled back if fn returns an error or
+// panics, in which case the panic is propagated after the rollback.
+//
+// If fn or the commit fails with a serialization failure (SQLSTATE 40001) or
+// a deadlock (SQLSTATE 40P01), as reported by pgerr.IsRetryable, the whole
+// transaction is run again after a jittered backoff, up to opts.MaxAttempts
+// times. fn must therefore be safe to run more than once. opts may be nil to
+// use the defaults.
+//
+// The ctx passed to fn carries the transaction. When WithTx is called with
+// such a ctx and the same db, fn is run in a savepoint of the enclosing
+// transaction by Tx.Savepoint instead, and opts is ignored. Retries are left
+// to the outermost WithTx, which runs the whole transaction again.
+func WithTx(ctx context.Context, db TxBeginner, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	if parent, ok := TxFromContext(ctx); ok && parent.beganOn(db) {
+		return parent.Savepoint(ctx, fn)
+	}
+	if db == nil {
+		return errors.New("pg: begin transaction: nil TxBeginner")
+	}
+
+	o := TxOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultTxMaxAttempts
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = DefaultTxBaseBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultTxMaxBackoff
+	}
+
+	backoff := o.BaseBackoff
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, &o, fn)
+		if err == nil || attempt >= o.MaxAttempts || !pgerr.IsRetryable(err) {
+			return err
+		}
+		timer := time.NewTimer(time.Duration(rand.Int63n(int64(backoff)) + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > o.MaxBackoff {
+			backoff = o.MaxBackoff
+		}
+	}
+}
+
+// runTx runs fn in a single transaction.
+func runTx(ctx context.Context, db TxBeginner, o *TxOptions, fn func(context.Context, *Tx) error) (err error) {
+	sqlTx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("pg: begin transaction: %w", err)
+	}
+	tx := &Tx{Tx: sqlTx, db: db, savepoints: new(atomic.Int64)}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+		if rbErr := sqlTx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			err = errors.Join(err, fmt.Errorf("pg: rollback: %w", rbErr))
+		}
+	}()
+
+	if err := fn(withTx(ctx, tx), tx); err != nil {
+		return err
+	}
+	committed = true
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("pg: commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 Bradley Bonitatibus
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeSQLStateError is a server error carrying a SQLSTATE code, in the shape
+// of the pgx and lib/pq error types.
+type fakeSQLStateError struct {
+	code string
+}
+
+func (e *fakeSQLStateError) Error() string {
+	return "server error " + e.code
+}
+
+func (e *fakeSQLStateError) SQLState() string {
+	return e.code
+}
+
+func newTxFixture(t *testing.T) (*fakeDriver, *sql.DB) {
+	t.Helper()
+	d, name := newFakeDriver(t)
+	db, err := sql.Open(name, "tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return d, db
+}
+
+func TestWithTx(t *testing.T) {
+	type test struct {
+		name     string
+		opts     *TxOptions
+		failures []error
+		commit   error
+		attempts int
+		log      []string
+		wantErr  bool
+	}
+	serialization := &fakeSQLStateError{code: "40001"}
+	deadlock := &fakeSQLStateError{code: "40P01"}
+	tests := []test{
+		{
+			name:     "commits on success",
+			attempts: 1,
+			log:      []string{"BEGIN", "UPDATE", "COMMIT"},
+		},
+		{
+			name:     "rolls back on error",
+			failures: []error{errors.New("boom")},
+			attempts: 1,
+			log:      []string{"BEGIN", "UPDATE", "ROLLBACK"},
+			wantErr:  true,
+		},
+		{
+			name:     "does not retry other SQLSTATEs",
+			failures: []error{&fakeSQLStateError{code: "23505"}},
+			attempts: 1,
+			log:      []string{"BEGIN", "UPDATE", "ROLLBACK"},
+			wantErr:  true,
+		},
+		{
+			name:     "retries serialization failures and deadlocks",
+			failures: []error{serialization, deadlock},
+			attempts: 3,
+			log:      []string{"BEGIN", "UPDATE", "ROLLBACK", "BEGIN", "UPDATE", "ROLLBACK", "BEGIN", "UPDATE", "COMMIT"},
+		},
+		{
+			name:     "gives up after max attempts",
+			opts:     &TxOptions{MaxAttempts: 2, BaseBackoff: time.Millisecond},
+			failures: []error{serialization, serialization, serialization},
+			attempts: 2,
+			log:      []string{"BEGIN", "UPDATE", "ROLLBACK", "BEGIN", "UPDATE", "ROLLBACK"},
+			wantErr:  true,
+		},
+		{
+			name:     "retries failed commits",
+			commit:   serialization,
+			attempts: 3,
+			log:      []string{"BEGIN", "UPDATE", "COMMIT", "BEGIN", "UPDATE", "COMMIT", "BEGIN", "UPDATE", "COMMIT"},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d, db := newTxFixture(t)
+			d.commit = func(string) error { return tc.commit }
+
+			attempts := 0
+			err := WithTx(context.Background(), db, tc.opts, func(ctx context.Context, tx *Tx) error {
+				if _, err := tx.ExecContext(ctx, "UPDATE"); err != nil {
+					return err
+				}
+				attempts++
+				if attempts <= len(tc.failures) {
+					return tc.failures[attempts-1]
+				}
+				return nil
+			})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v instead", tc.wantErr, err)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("expected %v attempts, got %v instead", tc.attempts, attempts)
+			}
+			if got := d.statements(); !reflect.DeepEqual(got, tc.log) {
+				t.Errorf("expected %v, got %v instead", tc.log, got)
+			}
+		})
+	}
+}
+
+func TestWithTx_Options(t *testing.T) {
+	d, db := newTxFixture(t)
+	var got driver.TxOptions
+	d.begin = func(_ string, opts driver.TxOptions) error {
+		got = opts
+		return nil
+	}
+	opts := &TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	err := WithTx(context.Background(), db, opts, func(context.Context, *Tx) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Isolation != driver.IsolationLevel(sql.LevelSerializable) || !got.ReadOnly {
+		t.Errorf("expected serializable read-only transaction, got %+v instead", got)
+	}
+}
+
+func TestWithTx_Panic(t *testing.T) {
+	d, db := newTxFixture(t)
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("expected panic to propagate, got %v instead", p)
+		}
+		want := []string{"BEGIN", "ROLLBACK"}
+		if got := d.statements(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v instead", want, got)
+		}
+	}()
+	WithTx(context.Background(), db, nil, func(context.Context, *Tx) error {
+		panic("boom")
+	})
+}
+
+func TestWithTx_Cancelled(t *testing.T) {
+	_, db := newTxFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &TxOptions{MaxAttempts: 5, BaseBackoff: time.Hour}
+	err := WithTx(ctx, db, opts, func(context.Context, *Tx) error {
+		cancel()
+		return &fakeSQLStateError{code: "40001"}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v instead", context.Canceled, err)
+	}
+}